@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// answerMessages are the message IDs confirming the answer to a poll, per WantLevel
+var answerMessages = map[WantLevel]string{
+	WLParticipate: "poll.answer.participate",
+	WLVolunteer:   "poll.answer.volunteer",
+	WLDecline:     "poll.answer.decline",
+}
+
+// rescheduleMinutes are the minutes, by which the buttons of the creator postpone a game
+var rescheduleMinutes = []int{5, 15}
+
+// decodeActionRequest reads the button click sent by Mattermost
+func decodeActionRequest(r *http.Request) (*model.PostActionIntegrationRequest, error) {
+	var request model.PostActionIntegrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// gameFromRequest resolves the game a button click belongs to. Returns nil if the game is over,
+// i.e. there is no game in the channel, or the button belongs to an earlier game.
+func (p *KickerPlugin) gameFromRequest(request *model.PostActionIntegrationRequest) *Game {
+	channelID := request.ChannelId
+	if channelID == "" {
+		// ephemeral posts do not carry a channel, so it is passed in the context
+		channelID, _ = request.Context["channel_id"].(string)
+	}
+
+	game := p.getGame(channelID)
+	if game == nil {
+		return nil
+	}
+	if gameID, _ := request.Context["game_id"].(string); gameID != game.id {
+		return nil
+	}
+	return game
+}
+
+// writeActionResponse answers a button click
+func writeActionResponse(w http.ResponseWriter, response *model.PostActionIntegrationResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response.ToJson())
+}
+
+// writeActionError answers a button click with the given status code and an error as JSON
+func writeActionError(w http.ResponseWriter, statusCode int, message string, err error) {
+	appErr := appError(message, err)
+	appErr.StatusCode = statusCode
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write([]byte(appErr.ToJson()))
+}
+
+// writePollOver tells the user who clicked a button of a finished game that the poll is over
+func (p *KickerPlugin) writePollOver(w http.ResponseWriter, userID string) {
+	writeActionResponse(w, &model.PostActionIntegrationResponse{
+		EphemeralText: p.userTranslator(userID)("poll.over"),
+	})
+}
+
+// handleParticipationRequest records the answer of the clicking user, and responds with
+// the updated poll and a confirmation only the user can see
+func (p *KickerPlugin) handleParticipationRequest(w http.ResponseWriter, r *http.Request, wantLevel WantLevel) {
+	userID := r.Header.Get("Mattermost-User-Id")
+	if userID == "" {
+		writeActionError(w, http.StatusUnauthorized, "not authorized", nil)
+		return
+	}
+
+	request, err := decodeActionRequest(r)
+	if err != nil {
+		writeActionError(w, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	game := p.gameFromRequest(request)
+	if game == nil {
+		p.writePollOver(w, userID)
+		return
+	}
+
+	err = p.setUserWantLevel(game, userID, wantLevel)
+	if err == errGameEnded {
+		p.writePollOver(w, userID)
+		return
+	}
+	if err != nil {
+		writeActionError(w, http.StatusInternalServerError, "failed to record answer", err)
+		return
+	}
+
+	writeActionResponse(w, &model.PostActionIntegrationResponse{
+		EphemeralText: p.userTranslator(userID)(answerMessages[wantLevel]),
+	})
+}
+
+// ParticipateHandler handles participation requests
+func (p *KickerPlugin) ParticipateHandler(w http.ResponseWriter, r *http.Request) {
+	p.handleParticipationRequest(w, r, WLParticipate)
+}
+
+// VolunteerHandler handles volunteering requests
+func (p *KickerPlugin) VolunteerHandler(w http.ResponseWriter, r *http.Request) {
+	p.handleParticipationRequest(w, r, WLVolunteer)
+}
+
+// DeclineHandler handles declining request
+func (p *KickerPlugin) DeclineHandler(w http.ResponseWriter, r *http.Request) {
+	p.handleParticipationRequest(w, r, WLDecline)
+}
+
+// CancelGameHandler handles canceling game requests
+func (p *KickerPlugin) CancelGameHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-Id")
+	if userID == "" {
+		writeActionError(w, http.StatusUnauthorized, "not authorized", nil)
+		return
+	}
+
+	request, err := decodeActionRequest(r)
+	if err != nil {
+		writeActionError(w, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	game := p.gameFromRequest(request)
+	if game == nil {
+		p.writePollOver(w, userID)
+		return
+	}
+
+	if !p.canManageGame(game, userID) {
+		writeActionError(w, http.StatusForbidden, "only the creator of the game and admins can cancel it", nil)
+		return
+	}
+
+	p.cancelGame(game, userID)
+
+	writeActionResponse(w, &model.PostActionIntegrationResponse{})
+}
+
+// RescheduleHandler handles postponing a game by the minutes given in the context of the button,
+// or starting it right away if no minutes are given
+func (p *KickerPlugin) RescheduleHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-Id")
+	if userID == "" {
+		writeActionError(w, http.StatusUnauthorized, "not authorized", nil)
+		return
+	}
+
+	request, err := decodeActionRequest(r)
+	if err != nil {
+		writeActionError(w, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	game := p.gameFromRequest(request)
+	if game == nil {
+		p.writePollOver(w, userID)
+		return
+	}
+
+	if !p.canManageGame(game, userID) {
+		writeActionError(w, http.StatusForbidden, "only the creator of the game and admins can reschedule it", nil)
+		return
+	}
+
+	// numbers in the context are decoded as float64; the game is postponed from its end under the lock,
+	// so that quick clicks add up
+	minutes, postpone := request.Context["minutes"].(float64)
+	endTime, err := p.rescheduleGame(game, func(current time.Time) time.Time {
+		if postpone {
+			return current.Add(time.Duration(minutes) * time.Minute)
+		}
+		return time.Now()
+	}, userID)
+	if err == errGameEnded {
+		p.writePollOver(w, userID)
+		return
+	}
+	if err != nil {
+		writeActionError(w, http.StatusInternalServerError, "failed to reschedule game", err)
+		return
+	}
+
+	tr := p.userTranslator(userID)
+	writeActionResponse(w, &model.PostActionIntegrationResponse{
+		EphemeralText: tr("command.reschedule.done", formatStartTime(endTime, p.getConfiguration().location, time.Now(), tr)),
+	})
+}
+
+// DropOutHandler handles players dropping out of the lineup of a result post
+func (p *KickerPlugin) DropOutHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-Id")
+	if userID == "" {
+		writeActionError(w, http.StatusUnauthorized, "not authorized", nil)
+		return
+	}
+
+	request, err := decodeActionRequest(r)
+	if err != nil {
+		writeActionError(w, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	lineupID, _ := request.Context["lineup_id"].(string)
+	if lineupID == "" {
+		writeActionError(w, http.StatusBadRequest, "missing lineup", nil)
+		return
+	}
+
+	err = p.dropOut(lineupID, userID)
+	if err == errNotInLineup {
+		writeActionResponse(w, &model.PostActionIntegrationResponse{
+			EphemeralText: p.userTranslator(userID)("lineup.not_in_lineup"),
+		})
+		return
+	}
+	if err != nil {
+		writeActionError(w, http.StatusInternalServerError, "failed to drop out", err)
+		return
+	}
+
+	writeActionResponse(w, &model.PostActionIntegrationResponse{
+		EphemeralText: p.userTranslator(userID)("lineup.dropped_out"),
+	})
+}
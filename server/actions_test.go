@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// sendClick sends a button click of the given user with the given context to the handler
+func sendClick(handler http.HandlerFunc, userID string, context map[string]interface{}) *httptest.ResponseRecorder {
+	request := &model.PostActionIntegrationRequest{UserId: userID, Context: context}
+	r := httptest.NewRequest("POST", "/participate", bytes.NewReader(request.ToJson()))
+	if userID != "" {
+		r.Header.Set("Mattermost-User-Id", userID)
+	}
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+// clickButton sends a button click like sendClick, and returns the decoded response
+func clickButton(handler http.HandlerFunc, userID string, context map[string]interface{}) *model.PostActionIntegrationResponse {
+	var response model.PostActionIntegrationResponse
+	json.Unmarshal(sendClick(handler, userID, context).Body.Bytes(), &response)
+	return &response
+}
+
+func TestStaleClick(t *testing.T) {
+	p := newTestPlugin(newFakeAPI(), loadTestTranslations(t))
+
+	canceled := startTestGame(p, "channel", time.Hour)
+	p.cancelGame(canceled, canceled.userID)
+
+	response := clickButton(p.ParticipateHandler, "1", actionContext(canceled))
+	if response.EphemeralText != "This poll is over." {
+		t.Errorf("Click on canceled game should tell that the poll is over, got: %+v", response)
+	}
+
+	game := startTestGame(p, "channel", time.Hour)
+	defer p.cancelGame(game, game.userID)
+
+	response = clickButton(p.ParticipateHandler, "1", actionContext(canceled))
+	if response.EphemeralText != "This poll is over." {
+		t.Errorf("Click on earlier game should tell that the poll is over, got: %+v", response)
+	}
+
+	response = clickButton(p.ParticipateHandler, "2", map[string]interface{}{"channel_id": "channel"})
+	if response.EphemeralText != "This poll is over." {
+		t.Errorf("Click without game ID should tell that the poll is over, got: %+v", response)
+	}
+
+	clickButton(p.ParticipateHandler, "3", actionContext(game))
+
+	game.lock.Lock()
+	defer game.lock.Unlock()
+	if len(game.participants) != 1 || game.participants[0].user.Id != "3" {
+		t.Errorf("Only the click on the current game should count, got: %v", game.participants)
+	}
+}
+
+func TestParticipationResponse(t *testing.T) {
+	p := newTestPlugin(newFakeAPI(), loadTestTranslations(t))
+	game := startTestGame(p, "channel", time.Hour)
+	defer p.cancelGame(game, game.userID)
+
+	response := clickButton(p.VolunteerHandler, "1", actionContext(game))
+	if response.EphemeralText != "You are in as volunteer, if nobody else dares." {
+		t.Errorf("Volunteering was not confirmed, got: %s", response.EphemeralText)
+	}
+	if response.Update != nil {
+		t.Errorf("Response should not replace the poll post, which is updated under the lock, got: %+v", response.Update)
+	}
+
+	game.lock.Lock()
+	defer game.lock.Unlock()
+	props, _ := json.Marshal(game.pollPost.Props)
+	if !strings.Contains(string(props), "👉: user1") {
+		t.Errorf("Updated poll post does not show the volunteer, got: %s", props)
+	}
+
+	tr := p.translations.translator(game.locale)
+	expected := "2v2 kicker starts at " + formatStartClock(game.endTime, p.getConfiguration().location, time.Now(), tr) + "."
+	if text := p.buildSlackAttachments(game)[0].Text; text != expected {
+		t.Errorf("Poll should show the format and start time, got: %s, want: %s", text, expected)
+	}
+}
+
+func TestActionErrors(t *testing.T) {
+	p := newTestPlugin(newFakeAPI(), loadTestTranslations(t))
+	game := startTestGame(p, "channel", time.Hour)
+	defer p.cancelGame(game, game.userID)
+
+	if w := sendClick(p.ParticipateHandler, "", actionContext(game)); w.Code != http.StatusUnauthorized {
+		t.Errorf("Click without user should be unauthorized, got: %d", w.Code)
+	}
+
+	r := httptest.NewRequest("POST", "/participate", strings.NewReader("no json"))
+	r.Header.Set("Mattermost-User-Id", "1")
+	w := httptest.NewRecorder()
+	p.ParticipateHandler(w, r)
+	var appErr model.AppError
+	if w.Code != http.StatusBadRequest || json.Unmarshal(w.Body.Bytes(), &appErr) != nil || appErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("Invalid click should be a bad request with JSON error, got: %d %s", w.Code, w.Body.String())
+	}
+
+	if w = sendClick(p.CancelGameHandler, "1", actionContext(game)); w.Code != http.StatusForbidden {
+		t.Errorf("Cancel by other user should be forbidden, got: %d", w.Code)
+	}
+
+	if w = sendClick(p.CancelGameHandler, game.userID, actionContext(game)); w.Code != http.StatusOK || p.getGame("channel") != nil {
+		t.Errorf("Cancel by creator should cancel the game, got: %d", w.Code)
+	}
+}
+
+func TestRescheduleHandler(t *testing.T) {
+	api := newFakeAPI()
+	api.admins["admin"] = true
+	p := newTestPlugin(api, loadTestTranslations(t))
+	game := startTestGame(p, "channel", time.Hour)
+
+	game.lock.Lock()
+	endTime := game.endTime
+	game.lock.Unlock()
+
+	context := actionContext(game)
+	context["minutes"] = 15
+
+	if w := sendClick(p.RescheduleHandler, "1", context); w.Code != http.StatusForbidden {
+		t.Errorf("Reschedule by other user should be forbidden, got: %d", w.Code)
+	}
+
+	// simultaneous clicks must both postpone the game
+	var wg sync.WaitGroup
+	for _, userID := range []string{game.userID, "admin"} {
+		wg.Add(1)
+		go func(userID string) {
+			defer wg.Done()
+			response := clickButton(p.RescheduleHandler, userID, context)
+			if !strings.HasPrefix(response.EphemeralText, "Kicker starts at ") {
+				t.Errorf("Reschedule by %s was not confirmed, got: %s", userID, response.EphemeralText)
+			}
+		}(userID)
+	}
+	wg.Wait()
+
+	game.lock.Lock()
+	if expected := endTime.Add(30 * time.Minute); !game.endTime.Equal(expected) {
+		t.Errorf("Game was not postponed, end time is %s, want: %s", game.endTime, expected)
+	}
+	game.lock.Unlock()
+
+	notices := 0
+	for _, message := range api.createdPosts() {
+		if strings.HasPrefix(message, "user"+game.userID+" moved the start") || strings.HasPrefix(message, "useradmin moved the start") {
+			notices++
+		}
+	}
+	if notices != 2 {
+		t.Errorf("Every reschedule should be announced, got: %v", api.createdPosts())
+	}
+
+	// starting right away ends the poll
+	clickButton(p.RescheduleHandler, game.userID, actionContext(game))
+	for deadline := time.Now().Add(time.Second); p.getGame("channel") != nil; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Game did not start right away")
+		}
+	}
+	if count := countEndPosts(api.createdPosts()); count != 1 {
+		t.Errorf("Game should end exactly once, got: %v", api.createdPosts())
+	}
+}
+
+func TestCancelByAdmin(t *testing.T) {
+	api := newFakeAPI()
+	api.admins["admin"] = true
+	p := newTestPlugin(api, loadTestTranslations(t))
+	game := startTestGame(p, "channel", time.Hour)
+
+	if w := sendClick(p.CancelGameHandler, "admin", actionContext(game)); w.Code != http.StatusOK || p.getGame("channel") != nil {
+		t.Fatalf("Cancel by admin should cancel the game, got: %d", w.Code)
+	}
+
+	posts := api.createdPosts()
+	if len(posts) != 1 || posts[0] != "useradmin stopped the bot!" {
+		t.Errorf("Cancellation post should name the admin, got: %v", posts)
+	}
+}
+
+func TestDropOutHandler(t *testing.T) {
+	api := newFakeAPI()
+	p := newTestPlugin(api, loadTestTranslations(t))
+	game := startTestGame(p, "channel", time.Hour)
+	for i := 1; i <= 5; i++ {
+		if err := p.setUserWantLevel(game, fmt.Sprintf("%d", i), WLParticipate); err != nil {
+			t.Fatalf("Click failed: %s", err)
+		}
+	}
+	p.CreateEndPollPost(game)
+
+	data, _ := json.Marshal(api.kv)
+	var lineup *Lineup
+	for key, value := range api.kv {
+		if strings.HasPrefix(key, lineupKeyPrefix) {
+			lineup = &Lineup{}
+			json.Unmarshal(value, lineup)
+		}
+	}
+	if lineup == nil || len(lineup.MatchIDs) != 1 || len(lineup.Waitlist) != 1 {
+		t.Fatalf("Lineup with one match and one waiting player should be stored, got: %s", data)
+	}
+	waitingID := lineup.Waitlist[0]
+	match, _ := p.getMatch(lineup.MatchIDs[0])
+	playerID := match.Teams[1][0]
+
+	response := clickButton(p.DropOutHandler, "6", map[string]interface{}{"lineup_id": lineup.ID})
+	if response.EphemeralText != "You neither play in a match without result nor are on the waitlist." || response.Update != nil {
+		t.Errorf("Click by other user should not change the lineup, got: %+v", response)
+	}
+
+	response = clickButton(p.DropOutHandler, playerID, map[string]interface{}{"lineup_id": lineup.ID})
+	if response.EphemeralText != "You are out." || response.Update != nil {
+		t.Fatalf("Drop out should be confirmed, got: %+v", response)
+	}
+	if post := api.post(lineup.PostID); strings.Contains(post.Message, "Waitlist") || !strings.Contains(post.Message, "user"+waitingID) {
+		t.Errorf("Waiting player should move up in the result post, got: %s", post.Message)
+	}
+
+	match, _ = p.getMatch(lineup.MatchIDs[0])
+	if match.Teams[1][0] != waitingID || match.HasPlayer(playerID) {
+		t.Errorf("Waiting player should take the position in the match, got: %v", match.Teams)
+	}
+
+	posts := api.createdPosts()
+	if expected := "@user" + waitingID + ", you move up for user" + playerID + "!"; posts[len(posts)-1] != expected {
+		t.Errorf("Substitute should be mentioned, got: %s, want: %s", posts[len(posts)-1], expected)
+	}
+	if messages := api.directMessages()[waitingID]; len(messages) != 1 || !strings.HasPrefix(messages[0], "The table is yours!") {
+		t.Errorf("Substitute should be told by direct message, got: %v", messages)
+	}
+
+	// nobody is left on the waitlist, the match is played short-handed and not rated
+	clickButton(p.DropOutHandler, match.Teams[0][0], map[string]interface{}{"lineup_id": lineup.ID})
+	if match, _ = p.getMatch(lineup.MatchIDs[0]); !match.ShortHanded || len(match.Teams[0]) != 1 {
+		t.Fatalf("Match should be short-handed, got: %+v", match)
+	}
+	p.executeResultCommand(&model.CommandArgs{UserId: match.Teams[1][0], ChannelId: "channel"}, []string{"10", "5"})
+	if posts = api.createdPosts(); !strings.HasSuffix(posts[len(posts)-1], p.channelTranslator("channel")("result.not_rated")) {
+		t.Errorf("Result should not be rated, got: %s", posts[len(posts)-1])
+	}
+	if rating, _ := p.getRating(match.Teams[1][0]); rating.Games != 0 {
+		t.Errorf("Ratings should not change, got: %+v", rating)
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+// directChannelPrefix prefixes the IDs of the direct channels of the bot, followed by the user-ID
+const directChannelPrefix = "dm_"
+
+// fakeAPI is an in-memory implementation of the parts of the plugin API used by the games.
+// Calling any other method panics, as the embedded interface is nil.
+type fakeAPI struct {
+	plugin.API
+
+	lock  sync.Mutex
+	kv    map[string][]byte
+	posts []*model.Post
+	// admins are the IDs of the users with all permissions
+	admins map[string]bool
+}
+
+func newFakeAPI() *fakeAPI {
+	return &fakeAPI{kv: map[string][]byte{}, admins: map[string]bool{}}
+}
+
+// newTestPlugin returns a plugin using a fakeAPI and the shipped translations
+func newTestPlugin(api *fakeAPI, translations translationBundle) *KickerPlugin {
+	p := &KickerPlugin{
+		games:        map[string]*Game{},
+		translations: translations,
+	}
+	p.SetAPI(api)
+	return p
+}
+
+// createdPosts returns the messages of all posts created in channels so far, without direct messages
+func (a *fakeAPI) createdPosts() []string {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	messages := []string{}
+	for _, post := range a.posts {
+		if !strings.HasPrefix(post.ChannelId, directChannelPrefix) {
+			messages = append(messages, post.Message)
+		}
+	}
+	return messages
+}
+
+// directMessages returns the messages of all direct messages sent so far, per user-ID
+func (a *fakeAPI) directMessages() map[string][]string {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	messages := map[string][]string{}
+	for _, post := range a.posts {
+		if strings.HasPrefix(post.ChannelId, directChannelPrefix) {
+			userID := strings.TrimPrefix(post.ChannelId, directChannelPrefix)
+			messages[userID] = append(messages[userID], post.Message)
+		}
+	}
+	return messages
+}
+
+func (a *fakeAPI) GetUser(userID string) (*model.User, *model.AppError) {
+	return &model.User{Id: userID, Username: "user" + userID, Locale: "en"}, nil
+}
+
+func (a *fakeAPI) CreatePost(post *model.Post) (*model.Post, *model.AppError) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	post.Id = model.NewId()
+	a.posts = append(a.posts, post)
+	return post, nil
+}
+
+func (a *fakeAPI) UpdatePost(post *model.Post) (*model.Post, *model.AppError) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	for i, existing := range a.posts {
+		if existing.Id == post.Id {
+			a.posts[i] = post
+		}
+	}
+	return post, nil
+}
+
+// post returns the created post with the given ID in its latest version, or nil
+func (a *fakeAPI) post(postID string) *model.Post {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	for _, post := range a.posts {
+		if post.Id == postID {
+			return post
+		}
+	}
+	return nil
+}
+
+func (a *fakeAPI) DeletePost(postID string) *model.AppError {
+	return nil
+}
+
+func (a *fakeAPI) SendEphemeralPost(userID string, post *model.Post) *model.Post {
+	post.Id = model.NewId()
+	return post
+}
+
+func (a *fakeAPI) DeleteEphemeralPost(userID, postID string) {}
+
+func (a *fakeAPI) KVSet(key string, value []byte) *model.AppError {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.kv[key] = value
+	return nil
+}
+
+func (a *fakeAPI) KVGet(key string) ([]byte, *model.AppError) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	return a.kv[key], nil
+}
+
+func (a *fakeAPI) KVDelete(key string) *model.AppError {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	delete(a.kv, key)
+	return nil
+}
+
+func (a *fakeAPI) KVList(page, perPage int) ([]string, *model.AppError) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	keys := []string{}
+	for key := range a.kv {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	if start := page * perPage; start < len(keys) {
+		keys = keys[start:]
+	} else {
+		keys = []string{}
+	}
+	if len(keys) > perPage {
+		keys = keys[:perPage]
+	}
+	return keys, nil
+}
+
+// GetChannel returns a channel named "channel-<ID>"
+func (a *fakeAPI) GetChannel(channelID string) (*model.Channel, *model.AppError) {
+	return &model.Channel{Id: channelID, Name: "channel-" + channelID}, nil
+}
+
+// GetChannelByName finds the channels named by GetChannel
+func (a *fakeAPI) GetChannelByName(teamID, name string, includeDeleted bool) (*model.Channel, *model.AppError) {
+	if !strings.HasPrefix(name, "channel-") {
+		return nil, model.NewAppError("GetChannelByName", "channel not found", nil, name, http.StatusNotFound)
+	}
+	return a.GetChannel(strings.TrimPrefix(name, "channel-"))
+}
+
+// GetDirectChannel returns the direct channel of the bot with the other user, see directChannelPrefix
+func (a *fakeAPI) GetDirectChannel(userID1, userID2 string) (*model.Channel, *model.AppError) {
+	return &model.Channel{Id: directChannelPrefix + userID2}, nil
+}
+
+func (a *fakeAPI) GetChannelMember(channelID, userID string) (*model.ChannelMember, *model.AppError) {
+	return &model.ChannelMember{ChannelId: channelID, UserId: userID}, nil
+}
+
+func (a *fakeAPI) LogError(msg string, keyValuePairs ...interface{}) {}
+
+func (a *fakeAPI) HasPermissionTo(userID string, permission *model.Permission) bool {
+	return a.admins[userID]
+}
+
+func (a *fakeAPI) HasPermissionToChannel(userID, channelID string, permission *model.Permission) bool {
+	return a.admins[userID]
+}
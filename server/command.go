@@ -0,0 +1,365 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// subcommand defines a subcommand of the slash command, e.g. "history" for /kicker history
+type subcommand struct {
+	name string
+	// hint describes the arguments, e.g. "[n]"
+	hint string
+	// arguments adds the suggestions for the arguments to the autocomplete data of the subcommand.
+	// Without, the hint is suggested as a single text argument.
+	arguments func(data *model.AutocompleteData)
+	// execute runs the subcommand with the arguments following its name
+	execute func(args *model.CommandArgs, params []string) (*model.CommandResponse, *model.AppError)
+}
+
+// subcommands returns the definitions of all subcommands, in the order they are listed in the help
+func (p *KickerPlugin) subcommands() []*subcommand {
+	return []*subcommand{
+		{
+			name:    "start",
+			hint:    "[--format 1v1] [time]",
+			execute: p.executeStartCommand,
+		},
+		{
+			name:    "cancel",
+			execute: p.executeCancelCommand,
+		},
+		{
+			name:    "reschedule",
+			hint:    "<time>",
+			execute: p.executeRescheduleCommand,
+		},
+		{
+			name:    "status",
+			execute: p.executeStatusCommand,
+		},
+		{
+			name: "schedule",
+			hint: "add <weekdays> <time> [~channel] | list | remove <n>",
+			arguments: func(data *model.AutocompleteData) {
+				add := model.NewAutocompleteData("add", "<weekdays> <time> [~channel]", "")
+				add.AddTextArgument("", "<weekdays> <time> [~channel]", "")
+				remove := model.NewAutocompleteData("remove", "<n>", "")
+				remove.AddTextArgument("", "<n>", "")
+				data.AddCommand(add)
+				data.AddCommand(model.NewAutocompleteData("list", "", ""))
+				data.AddCommand(remove)
+			},
+			execute: p.executeScheduleCommand,
+		},
+		{
+			name:      "join",
+			hint:      "[volunteer]",
+			arguments: listArgument("volunteer"),
+			execute:   p.executeJoinCommand,
+		},
+		{
+			name:    "leave",
+			execute: p.executeLeaveCommand,
+		},
+		{
+			name:    "prefs",
+			hint:    "[<weekdays> <participate|volunteer|decline|none>]",
+			execute: p.executePrefsCommand,
+		},
+		{
+			name:      "reminders",
+			hint:      "[on|off]",
+			arguments: listArgument("on", "off"),
+			execute:   p.executeRemindersCommand,
+		},
+		{
+			name: "recruit",
+			hint: "[off|mention|dm] [n]",
+			arguments: func(data *model.AutocompleteData) {
+				listArgument(recruitModes...)(data)
+				data.AddTextArgument("", "[n]", "")
+			},
+			execute: p.executeRecruitCommand,
+		},
+		{
+			name:    "result",
+			hint:    "<goals team A> <goals team B>",
+			execute: p.executeResultCommand,
+		},
+		{
+			name:    "history",
+			hint:    "[n]",
+			execute: p.executeHistoryCommand,
+		},
+		{
+			name:    "rating",
+			hint:    "[@user]",
+			execute: p.executeRatingCommand,
+		},
+		{
+			name:      "leaderboard",
+			hint:      "[week|month|all]",
+			arguments: listArgument("week", "month", "all"),
+			execute:   p.executeLeaderboardCommand,
+		},
+		{
+			name:      "language",
+			hint:      "<" + strings.Join(p.translations.locales(), "|") + ">",
+			arguments: listArgument(p.translations.locales()...),
+			execute:   p.executeLanguageCommand,
+		},
+		{
+			name:    "help",
+			execute: p.executeHelpCommand,
+		},
+	}
+}
+
+// findSubcommand returns the subcommand with the given name, or nil
+func (p *KickerPlugin) findSubcommand(name string) *subcommand {
+	for _, sc := range p.subcommands() {
+		if sc.name == strings.ToLower(name) {
+			return sc
+		}
+	}
+	return nil
+}
+
+// listArgument returns the arguments of a subcommand, which takes one of the given items
+func listArgument(items ...string) func(data *model.AutocompleteData) {
+	return func(data *model.AutocompleteData) {
+		listItems := []model.AutocompleteListItem{}
+		for _, item := range items {
+			listItems = append(listItems, model.AutocompleteListItem{Item: item})
+		}
+		data.AddStaticListArgument("", false, listItems)
+	}
+}
+
+// autocompleteHint returns the hint shown by the client, listing all subcommands
+func (p *KickerPlugin) autocompleteHint() string {
+	names := []string{}
+	for _, sc := range p.subcommands() {
+		names = append(names, sc.name)
+	}
+	return "[time] | [" + strings.Join(names, "|") + "]"
+}
+
+// autocompleteData returns the suggestions of the client for the subcommands and their arguments
+func (p *KickerPlugin) autocompleteData(tr translateFunc) *model.AutocompleteData {
+	data := model.NewAutocompleteData(strings.ToLower(p.getConfiguration().Trigger), p.autocompleteHint(), tr("command.description"))
+	for _, sc := range p.subcommands() {
+		subcommandData := model.NewAutocompleteData(sc.name, sc.hint, tr("command."+sc.name+".help"))
+		if sc.arguments != nil {
+			sc.arguments(subcommandData)
+		} else if sc.hint != "" {
+			subcommandData.AddTextArgument("", sc.hint, "")
+		}
+		data.AddCommand(subcommandData)
+	}
+	return data
+}
+
+// executeCommand dispatches the command to its subcommand. Without a known subcommand, a game is started.
+func (p *KickerPlugin) executeCommand(args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+	params := strings.Fields(args.Command)[1:]
+
+	if len(params) > 0 {
+		if sc := p.findSubcommand(params[0]); sc != nil {
+			return sc.execute(args, params[1:])
+		}
+	}
+
+	return p.executeStartCommand(args, params)
+}
+
+// executeHelpCommand lists all subcommands with their arguments
+func (p *KickerPlugin) executeHelpCommand(args *model.CommandArgs, params []string) (*model.CommandResponse, *model.AppError) {
+	return ephemeralResponse(p.helpText(p.userTranslator(args.UserId))), nil
+}
+
+// helpText returns the Markdown list of all subcommands with their arguments and descriptions
+func (p *KickerPlugin) helpText(tr translateFunc) string {
+	trigger := p.getConfiguration().Trigger
+
+	text := "#### /" + trigger + "\n"
+	for _, sc := range p.subcommands() {
+		usage := "/" + trigger + " " + sc.name
+		if sc.hint != "" {
+			usage += " " + sc.hint
+		}
+		text += fmt.Sprintf("- `%s`: %s\n", usage, tr("command."+sc.name+".help"))
+	}
+	return text
+}
+
+// executeCancelCommand cancels the game running in the channel
+func (p *KickerPlugin) executeCancelCommand(args *model.CommandArgs, params []string) (*model.CommandResponse, *model.AppError) {
+	game := p.getGame(args.ChannelId)
+	if game == nil {
+		return ephemeralResponse(p.userTranslator(args.UserId)("command.no_game")), nil
+	}
+
+	if !p.canManageGame(game, args.UserId) {
+		return ephemeralResponse(p.userTranslator(args.UserId)("command.cancel.not_allowed")), nil
+	}
+
+	p.cancelGame(game, args.UserId)
+
+	return ephemeralResponse(""), nil
+}
+
+// executeRescheduleCommand moves the start of the game running in the channel to the time given by params
+func (p *KickerPlugin) executeRescheduleCommand(args *model.CommandArgs, params []string) (*model.CommandResponse, *model.AppError) {
+	user, appErr := p.API.GetUser(args.UserId)
+	if appErr != nil {
+		return nil, appErr
+	}
+	tr := p.translations.translator(user.Locale)
+
+	game := p.getGame(args.ChannelId)
+	if game == nil {
+		return ephemeralResponse(tr("command.no_game")), nil
+	}
+
+	if !p.canManageGame(game, args.UserId) {
+		return ephemeralResponse(tr("command.reschedule.not_allowed")), nil
+	}
+
+	if len(params) == 0 {
+		return ephemeralResponse(tr("command.reschedule.usage", p.getConfiguration().Trigger)), nil
+	}
+
+	configuration := p.getConfiguration()
+	endTime, parseError := ParseTime(params, time.Now().In(userLocation(user, configuration.location)), configuration.defaultHour)
+	if parseError != nil {
+		return ephemeralResponse(parseError.translate(tr) + " " + tr("time.error.examples")), nil
+	}
+
+	_, err := p.rescheduleGame(game, func(time.Time) time.Time { return endTime }, args.UserId)
+	if err == errGameEnded {
+		return ephemeralResponse(tr("command.no_game")), nil
+	}
+	if err != nil {
+		return nil, appError("failed to reschedule game", err)
+	}
+
+	return ephemeralResponse(tr("command.reschedule.done", formatStartTime(endTime, configuration.location, time.Now(), tr))), nil
+}
+
+// executeStatusCommand summarizes the game running in the channel. The creator and admins also get
+// the buttons to postpone, start or stop it, as the ephemeral post with the buttons may be gone.
+func (p *KickerPlugin) executeStatusCommand(args *model.CommandArgs, params []string) (*model.CommandResponse, *model.AppError) {
+	tr := p.userTranslator(args.UserId)
+	game := p.getGame(args.ChannelId)
+	if game == nil {
+		return ephemeralResponse(tr("command.no_game")), nil
+	}
+
+	game.lock.Lock()
+	defer game.lock.Unlock()
+	if game.ended {
+		return ephemeralResponse(tr("command.no_game")), nil
+	}
+
+	response := ephemeralResponse(p.statusText(game, time.Now(), tr))
+	if p.canManageGame(game, args.UserId) {
+		response.Attachments = []*model.SlackAttachment{{
+			Actions: p.buildGameControls(game, tr),
+		}}
+	}
+	return response, nil
+}
+
+// statusText returns the summary of the given game shown by the status command. The game must be locked.
+func (p *KickerPlugin) statusText(game *Game, now time.Time, tr translateFunc) string {
+	configuration := p.getConfiguration()
+	cache := map[string]string{}
+
+	lines := []string{
+		"#### " + tr("status.title", p.channelName(game.channelID)),
+		"- " + tr("status.creator", p.getUsernames([]string{game.userID}, cache)[0]),
+		"- " + tr("status.start", formatStartTime(game.endTime, configuration.location, now, tr)),
+		"- " + tr("status.format", formatName(game.teamSize)),
+	}
+
+	answers := []struct {
+		id      string
+		players []Player
+	}{
+		{"status.participants", game.GetParticipants()},
+		{"status.volunteers", game.GetVolunteers()},
+		{"status.decliners", game.GetDecliners()},
+	}
+	for _, answer := range answers {
+		names := "–"
+		if len(answer.players) > 0 {
+			names = joinAnswerNames(answer.players, tr)
+		}
+		lines = append(lines, "- "+tr(answer.id, len(answer.players), names))
+	}
+
+	warnTime := game.endTime.Add(-configuration.warnDuration)
+	switch {
+	case game.warned:
+		lines = append(lines, "- "+tr("status.warning.posted"))
+	case configuration.warnDuration == 0:
+		lines = append(lines, "- "+tr("status.warning.off"))
+	case warnTime.After(now):
+		lines = append(lines, "- "+tr("status.warning.pending", formatRelativeDuration(warnTime.Sub(now), tr)))
+	default:
+		lines = append(lines, "- "+tr("status.warning.none"))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// executeJoinCommand adds the user to the game running in the channel
+func (p *KickerPlugin) executeJoinCommand(args *model.CommandArgs, params []string) (*model.CommandResponse, *model.AppError) {
+	wantLevel := WLParticipate
+	if len(params) > 0 {
+		if params[0] != "volunteer" {
+			return ephemeralResponse(p.userTranslator(args.UserId)("command.join.usage")), nil
+		}
+		wantLevel = WLVolunteer
+	}
+
+	game := p.getGame(args.ChannelId)
+	if game == nil {
+		return ephemeralResponse(p.userTranslator(args.UserId)("command.no_game")), nil
+	}
+
+	err := p.setUserWantLevel(game, args.UserId, wantLevel)
+	if err == errGameEnded {
+		return ephemeralResponse(p.userTranslator(args.UserId)("command.no_game")), nil
+	}
+	if err != nil {
+		return nil, appError("failed to join game", err)
+	}
+
+	return ephemeralResponse(p.userTranslator(args.UserId)("command.join.done")), nil
+}
+
+// executeLeaveCommand removes the user from the game running in the channel
+func (p *KickerPlugin) executeLeaveCommand(args *model.CommandArgs, params []string) (*model.CommandResponse, *model.AppError) {
+	game := p.getGame(args.ChannelId)
+	if game == nil {
+		return ephemeralResponse(p.userTranslator(args.UserId)("command.no_game")), nil
+	}
+
+	game.lock.Lock()
+	defer game.lock.Unlock()
+	if game.ended {
+		return ephemeralResponse(p.userTranslator(args.UserId)("command.no_game")), nil
+	}
+
+	game.removeParticipantByID(args.UserId)
+	p.updatePollPost(game)
+	p.saveGame(game)
+
+	return ephemeralResponse(p.userTranslator(args.UserId)("command.leave.done")), nil
+}
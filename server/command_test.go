@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+func TestFindSubcommand(t *testing.T) {
+	p := &KickerPlugin{}
+
+	for _, name := range []string{"start", "cancel", "status", "join", "leave", "help", "History"} {
+		if sc := p.findSubcommand(name); sc == nil || sc.name != strings.ToLower(name) {
+			t.Errorf("Subcommand %s was not found", name)
+		}
+	}
+
+	for _, name := range []string{"12:30", "in", "now", "tomorrow", ""} {
+		if sc := p.findSubcommand(name); sc != nil {
+			t.Errorf("Time expression %s should not be a subcommand, got: %s", name, sc.name)
+		}
+	}
+}
+
+func TestHelpText(t *testing.T) {
+	p := &KickerPlugin{translations: loadTestTranslations(t)}
+
+	text := p.helpText(p.translations.translator("de"))
+
+	for _, sc := range p.subcommands() {
+		if !strings.Contains(text, "`/kicker "+sc.name) {
+			t.Errorf("Help does not list subcommand %s: %s", sc.name, text)
+		}
+		if strings.Contains(text, "command."+sc.name+".help") {
+			t.Errorf("Help of subcommand %s is not translated: %s", sc.name, text)
+		}
+	}
+
+	if !strings.Contains(text, "`/kicker history [n]`: Zeigt die letzten Spiele in diesem Kanal.") {
+		t.Errorf("Help does not show the arguments of history: %s", text)
+	}
+
+	if !strings.Contains(text, "`/kicker language <de|en>`") {
+		t.Errorf("Help does not show the available languages: %s", text)
+	}
+}
+
+func TestAutocompleteData(t *testing.T) {
+	p := &KickerPlugin{translations: loadTestTranslations(t)}
+
+	data := p.autocompleteData(p.translations.translator("en"))
+
+	if data.Trigger != "kicker" {
+		t.Errorf("Trigger is %q, expected kicker", data.Trigger)
+	}
+	subcommandData := map[string]*model.AutocompleteData{}
+	for _, sd := range data.SubCommands {
+		subcommandData[sd.Trigger] = sd
+	}
+	for _, sc := range p.subcommands() {
+		sd, ok := subcommandData[sc.name]
+		if !ok {
+			t.Errorf("Autocomplete does not suggest subcommand %s", sc.name)
+			continue
+		}
+		if strings.HasPrefix(sd.HelpText, "command.") {
+			t.Errorf("Help of subcommand %s is not translated: %s", sc.name, sd.HelpText)
+		}
+	}
+
+	leaderboard := subcommandData["leaderboard"]
+	if leaderboard == nil || len(leaderboard.Arguments) != 1 {
+		t.Fatalf("Unexpected autocomplete of leaderboard: %+v", leaderboard)
+	}
+	list, ok := leaderboard.Arguments[0].Data.(*model.AutocompleteStaticListArg)
+	if !ok || len(list.PossibleArguments) != 3 || list.PossibleArguments[2].Item != "all" {
+		t.Errorf("Leaderboard does not suggest its periods: %+v", leaderboard.Arguments[0].Data)
+	}
+
+	if history := subcommandData["history"]; history == nil || len(history.Arguments) != 1 {
+		t.Errorf("History does not suggest its hint as argument: %+v", history)
+	}
+
+	if schedule := subcommandData["schedule"]; schedule == nil || len(schedule.Arguments) != 0 || len(schedule.SubCommands) != 3 {
+		t.Errorf("Schedule does not suggest add, list and remove: %+v", schedule)
+	}
+}
+
+func TestStatusCommand(t *testing.T) {
+	api := newFakeAPI()
+	api.admins["admin"] = true
+	p := newTestPlugin(api, loadTestTranslations(t))
+
+	args := &model.CommandArgs{UserId: "1", ChannelId: "channel"}
+	if response, _ := p.executeStatusCommand(args, nil); response.Text != "There is no game running in this channel." {
+		t.Errorf("Status without game should say so, got: %s", response.Text)
+	}
+
+	game := startTestGame(p, "channel", time.Hour)
+	defer p.CreateEndPollPost(game)
+	for i, wantLevel := range []WantLevel{WLParticipate, WLParticipate, WLVolunteer} {
+		if err := p.setUserWantLevel(game, fmt.Sprintf("%d", i+1), wantLevel); err != nil {
+			t.Fatalf("Click failed: %s", err)
+		}
+	}
+
+	response, _ := p.executeStatusCommand(args, nil)
+	for _, expected := range []string{
+		"#### Kicker in ~channel-channel\n",
+		"- Started by: usercreator\n",
+		"in 1 hour)\n",
+		"- Format: 2v2\n",
+		"- 👍 In (2): user1, user2\n",
+		"- 👉 Volunteers (1): user3\n",
+		"- 👎 Out (0): –\n",
+		"- If players are missing, a warning is posted in 45 minutes.",
+	} {
+		if !strings.Contains(response.Text, expected) {
+			t.Errorf("Status should contain %q, got: %s", expected, response.Text)
+		}
+	}
+	if len(response.Attachments) != 0 {
+		t.Errorf("Players should not get the buttons to manage the game, got: %v", response.Attachments)
+	}
+
+	p.CheckEnoughPlayer(game)
+	args.UserId = "admin"
+	response, _ = p.executeStatusCommand(args, nil)
+	if !strings.Contains(response.Text, "- The warning about missing players was posted.") {
+		t.Errorf("Status should show the posted warning, got: %s", response.Text)
+	}
+	if len(response.Attachments) != 1 || len(response.Attachments[0].Actions) != len(rescheduleMinutes)+2 {
+		t.Errorf("Admin should get the buttons to manage the game, got: %v", response.Attachments)
+	}
+}
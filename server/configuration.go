@@ -2,10 +2,23 @@ package main
 
 import (
 	"reflect"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/mattermost/mattermost-server/v5/model"
 	"github.com/pkg/errors"
 )
 
+const (
+	// holidayDateFormat is the format of holidays on a single date
+	holidayDateFormat = "2006-01-02"
+	// holidayDayFormat is the format of holidays on the same day every year
+	holidayDayFormat = "01-02"
+	// maxScheduleLeadMinutes limits the schedule lead minutes to a day, so that polls do not overlap
+	maxScheduleLeadMinutes = 24 * 60
+)
+
 // configuration captures the plugin's external configuration as exposed in the Mattermost server
 // configuration, as well as values computed from the configuration. Any public fields will be
 // deserialized from the Mattermost server configuration in OnConfigurationChange.
@@ -18,6 +31,208 @@ import (
 // If you add non-reference types to your configuration struct, be sure to rewrite Clone as a deep
 // copy appropriate for your types.
 type configuration struct {
+	// Trigger is the slash command word, e.g. "kicker" for /kicker
+	Trigger string
+	// BotUserName is the username of the bot, which creates the posts
+	BotUserName string
+	// BotDisplayName is the name the bot uses in its posts
+	BotDisplayName string
+	// DefaultFormat is the format of games started without --format, e.g. "2v2"
+	DefaultFormat string
+	// Tables is the number of kicker tables, i.e. the maximum number of matches played at the same time
+	Tables string
+	// FairnessStrength controls how much players, who played less recently, are preferred; 0 for pure chance
+	FairnessStrength string
+	// WarnMinutes is the number of minutes before the start, when to warn about missing players; 0 to disable it
+	WarnMinutes string
+	// RecruitMode is how the regulars are recruited in channels without own setting, if a poll lacks players; see recruitModes
+	RecruitMode string
+	// RecruitLimit is the maximum number of regulars recruited per poll; channels can only set a lower limit
+	RecruitLimit string
+	// ReminderMinutes is the number of minutes before the start, when to remind the signed up players by direct message; 0 to disable it
+	ReminderMinutes string
+	// TimeZone is the name of the location, in which start times are shown, and interpreted for users without timezone
+	TimeZone string
+	// DefaultHour is the start hour used, if /kicker is called without a time
+	DefaultHour string
+	// ScheduleLeadMinutes is the number of minutes before the start of a recurring game, when its poll is started
+	ScheduleLeadMinutes string
+	// Holidays are the comma-separated dates without recurring games, e.g. "2019-12-24, 12-25" (the latter every year)
+	Holidays string
+	// LeaderboardChannelID is the channel, into which the weekly leaderboard is posted; empty to disable it
+	LeaderboardChannelID string
+	// LeaderboardWeekday is the English name of the weekday, on which the weekly leaderboard is posted
+	LeaderboardWeekday string
+	// LeaderboardHour is the hour, at which the weekly leaderboard is posted
+	LeaderboardHour string
+	// Locale is the language of public posts in channels without own language; empty for the server default
+	Locale string
+
+	// values computed from the settings above by process
+	defaultTeamSize    int
+	tables             int
+	fairnessStrength   float64
+	warnDuration       time.Duration
+	recruitLimit       int
+	remindDuration     time.Duration
+	location           *time.Location
+	defaultHour        int
+	scheduleLead       time.Duration
+	holidays           map[string]bool
+	leaderboardWeekday time.Weekday
+	leaderboardHour    int
+}
+
+// defaultConfiguration returns the configuration used until the server configuration was loaded.
+// The values are the same as the defaults in plugin.json.
+func defaultConfiguration() *configuration {
+	c := &configuration{
+		Trigger:          "kicker",
+		BotUserName:      "kicker",
+		BotDisplayName:   "kicker BOT",
+		DefaultFormat:    "2v2",
+		Tables:           "1",
+		FairnessStrength: "1",
+		WarnMinutes:      "15",
+		RecruitMode:      recruitMention,
+		RecruitLimit:     "5",
+		ReminderMinutes:  "5",
+		TimeZone:         "Europe/Berlin",
+		DefaultHour:      "12",
+
+		ScheduleLeadMinutes: "120",
+
+		LeaderboardWeekday: "Friday",
+		LeaderboardHour:    "16",
+
+		Locale: "de",
+	}
+	if err := c.process(); err != nil {
+		panic("invalid default configuration: " + err.Error())
+	}
+	return c
+}
+
+// process validates the public settings, and fills the computed values.
+func (c *configuration) process() error {
+	c.Trigger = strings.TrimSpace(c.Trigger)
+	if c.Trigger == "" || strings.ContainsAny(c.Trigger, " /") {
+		return errors.Errorf("trigger %q must be a single word without slashes", c.Trigger)
+	}
+
+	if !model.IsValidUsername(c.BotUserName) {
+		return errors.Errorf("bot username %q is invalid", c.BotUserName)
+	}
+
+	if strings.TrimSpace(c.BotDisplayName) == "" {
+		return errors.New("bot display name must not be empty")
+	}
+
+	defaultTeamSize, ok := parseFormat(c.DefaultFormat)
+	if !ok {
+		return errors.Errorf("default format %q must be like 2v2, with teams of 1 to %d players", c.DefaultFormat, maxTeamSize)
+	}
+	c.defaultTeamSize = defaultTeamSize
+
+	tables, err := strconv.Atoi(strings.TrimSpace(c.Tables))
+	if err != nil || tables < 1 {
+		return errors.Errorf("tables %q must be a number of at least 1", c.Tables)
+	}
+	c.tables = tables
+
+	fairnessStrength, err := strconv.ParseFloat(strings.TrimSpace(c.FairnessStrength), 64)
+	if err != nil || fairnessStrength < 0 || fairnessStrength > maxFairnessStrength {
+		return errors.Errorf("fairness strength %q must be a number between 0 and %d", c.FairnessStrength, maxFairnessStrength)
+	}
+	c.fairnessStrength = fairnessStrength
+
+	warnMinutes, err := strconv.Atoi(strings.TrimSpace(c.WarnMinutes))
+	if err != nil || warnMinutes < 0 {
+		return errors.Errorf("warn minutes %q must be a number of at least 0", c.WarnMinutes)
+	}
+	c.warnDuration = time.Minute * time.Duration(warnMinutes)
+
+	if !isRecruitMode(c.RecruitMode) {
+		return errors.Errorf("recruit mode %q must be one of %s", c.RecruitMode, strings.Join(recruitModes, ", "))
+	}
+
+	recruitLimit, err := strconv.Atoi(strings.TrimSpace(c.RecruitLimit))
+	if err != nil || recruitLimit < 1 {
+		return errors.Errorf("recruit limit %q must be a number of at least 1", c.RecruitLimit)
+	}
+	c.recruitLimit = recruitLimit
+
+	reminderMinutes, err := strconv.Atoi(strings.TrimSpace(c.ReminderMinutes))
+	if err != nil || reminderMinutes < 0 {
+		return errors.Errorf("reminder minutes %q must be a number of at least 0", c.ReminderMinutes)
+	}
+	c.remindDuration = time.Minute * time.Duration(reminderMinutes)
+
+	location, err := time.LoadLocation(strings.TrimSpace(c.TimeZone))
+	if err != nil {
+		return errors.Wrapf(err, "time zone %q is invalid", c.TimeZone)
+	}
+	c.location = location
+
+	defaultHour, err := strconv.Atoi(strings.TrimSpace(c.DefaultHour))
+	if err != nil || defaultHour < 0 || defaultHour >= paramMaxHour {
+		return errors.Errorf("default hour %q must be a number between 0 and %d", c.DefaultHour, paramMaxHour-1)
+	}
+	c.defaultHour = defaultHour
+
+	scheduleLeadMinutes, err := strconv.Atoi(strings.TrimSpace(c.ScheduleLeadMinutes))
+	if err != nil || scheduleLeadMinutes < 1 || scheduleLeadMinutes > maxScheduleLeadMinutes {
+		return errors.Errorf("schedule lead minutes %q must be a number between 1 and %d", c.ScheduleLeadMinutes, maxScheduleLeadMinutes)
+	}
+	c.scheduleLead = time.Minute * time.Duration(scheduleLeadMinutes)
+
+	c.holidays = map[string]bool{}
+	for _, holiday := range strings.Split(c.Holidays, ",") {
+		holiday = strings.TrimSpace(holiday)
+		if holiday == "" {
+			continue
+		}
+		if _, dateErr := time.Parse(holidayDateFormat, holiday); dateErr != nil {
+			if _, dayErr := time.Parse(holidayDayFormat, holiday); dayErr != nil {
+				return errors.Errorf("holiday %q must be a date like 2019-12-24, or 12-24 for every year", holiday)
+			}
+		}
+		c.holidays[holiday] = true
+	}
+
+	c.LeaderboardChannelID = strings.TrimSpace(c.LeaderboardChannelID)
+	if c.LeaderboardChannelID != "" && !model.IsValidId(c.LeaderboardChannelID) {
+		return errors.Errorf("leaderboard channel ID %q is invalid", c.LeaderboardChannelID)
+	}
+
+	leaderboardWeekday, err := parseWeekday(c.LeaderboardWeekday)
+	if err != nil {
+		return err
+	}
+	c.leaderboardWeekday = leaderboardWeekday
+
+	leaderboardHour, err := strconv.Atoi(strings.TrimSpace(c.LeaderboardHour))
+	if err != nil || leaderboardHour < 0 || leaderboardHour >= paramMaxHour {
+		return errors.Errorf("leaderboard hour %q must be a number between 0 and %d", c.LeaderboardHour, paramMaxHour-1)
+	}
+	c.leaderboardHour = leaderboardHour
+
+	return nil
+}
+
+// isHoliday checks if the given day is one of the configured holidays
+func (c *configuration) isHoliday(day time.Time) bool {
+	return c.holidays[day.Format(holidayDateFormat)] || c.holidays[day.Format(holidayDayFormat)]
+}
+
+// parseWeekday returns the weekday with the given English name
+func parseWeekday(name string) (time.Weekday, error) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(day.String(), strings.TrimSpace(name)) {
+			return day, nil
+		}
+	}
+	return time.Sunday, errors.Errorf("weekday %q is invalid", name)
 }
 
 // Clone shallow copies the configuration. Your implementation may require a deep copy if
@@ -35,7 +250,7 @@ func (p *KickerPlugin) getConfiguration() *configuration {
 	defer p.configurationLock.RUnlock()
 
 	if p.configuration == nil {
-		return &configuration{}
+		return defaultConfiguration()
 	}
 
 	return p.configuration
@@ -69,6 +284,7 @@ func (p *KickerPlugin) setConfiguration(configuration *configuration) {
 }
 
 // OnConfigurationChange is invoked when configuration changes may have been made.
+// An invalid configuration is rejected, and the previous configuration stays active.
 func (p *KickerPlugin) OnConfigurationChange() error {
 	var configuration = new(configuration)
 
@@ -77,7 +293,42 @@ func (p *KickerPlugin) OnConfigurationChange() error {
 		return errors.Wrap(err, "failed to load plugin configuration")
 	}
 
+	if err := configuration.process(); err != nil {
+		return errors.Wrap(err, "invalid plugin configuration")
+	}
+
+	previous := p.getConfiguration()
 	p.setConfiguration(configuration)
 
+	// command and bot are registered by OnActivate, so only update them if already active
+	if p.enabled {
+		if err := p.applyConfiguration(previous, configuration); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyConfiguration updates the registered command and the bot, if the corresponding settings changed.
+func (p *KickerPlugin) applyConfiguration(previous, current *configuration) error {
+	if previous.Trigger != current.Trigger {
+		if err := p.API.UnregisterCommand("", previous.Trigger); err != nil {
+			return errors.Wrap(err, "failed to unregister command")
+		}
+		if err := p.registerCommand(); err != nil {
+			return errors.Wrap(err, "failed to register command")
+		}
+	}
+
+	if previous.BotUserName != current.BotUserName || previous.BotDisplayName != current.BotDisplayName {
+		if _, appErr := p.API.PatchBot(p.botUserID, &model.BotPatch{
+			Username:    &current.BotUserName,
+			DisplayName: &current.BotDisplayName,
+		}); appErr != nil {
+			return errors.Wrap(appErr, "failed to update bot")
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"time"
+)
+
+const (
+	// leftOutKeyPrefix prefixes the KV store keys of the left out counts per channel, followed by the channel-ID
+	leftOutKeyPrefix = "leftout_"
+	// fairnessHistoryCount is the number of recent matches of the channel, which lower the chance to be chosen
+	fairnessHistoryCount = 10
+	// fairnessDecay is the weight of a match in the history, relative to the next newer match
+	fairnessDecay = 0.7
+	// maxFairnessStrength limits the fairness strength, beyond which the weights only cause overflows
+	maxFairnessStrength = 10
+)
+
+func leftOutKey(channelID string) string {
+	return leftOutKeyPrefix + channelID
+}
+
+// fairnessWeights returns the weight, with which each of the given players is chosen. Players who played
+// often and recently in the given matches (newest first) get a lower weight, players who were left out
+// in the last polls (see leftOut) a higher one. The strength scales the effect, 0 disables the weighting.
+func fairnessWeights(players []Player, matches []*Match, leftOut map[string]int, strength float64) map[string]float64 {
+	if strength <= 0 {
+		return nil
+	}
+
+	played := map[string]float64{}
+	recency := 1.0
+	for _, match := range matches {
+		for _, userID := range match.Players() {
+			played[userID] += recency
+		}
+		recency *= fairnessDecay
+	}
+
+	// the highest exponent is subtracted, so that the weights are at most 1 and do not overflow
+	exponents := map[string]float64{}
+	maxExponent := math.Inf(-1)
+	for _, player := range players {
+		userID := player.user.Id
+		exponents[userID] = strength * (float64(leftOut[userID]) - played[userID])
+		maxExponent = math.Max(maxExponent, exponents[userID])
+	}
+
+	weights := map[string]float64{}
+	for userID, exponent := range exponents {
+		weights[userID] = math.Exp(exponent - maxExponent)
+	}
+	return weights
+}
+
+// weightedIndex returns the index of a random player, chosen with the given weights.
+// Without weights, or if they do not sum up to a finite positive number, every player has the same chance.
+func weightedIndex(players []Player, weights map[string]float64) int {
+	if weights == nil {
+		return rand.Intn(len(players))
+	}
+
+	sum := 0.0
+	for _, player := range players {
+		sum += weights[player.user.Id]
+	}
+	if !(sum > 0) || math.IsInf(sum, 0) {
+		return rand.Intn(len(players))
+	}
+
+	// rounding may leave a rest, which falls to the last player with a weight
+	last := 0
+	r := rand.Float64() * sum
+	for i, player := range players {
+		if weights[player.user.Id] <= 0 {
+			continue
+		}
+		last = i
+		r -= weights[player.user.Id]
+		if r < 0 {
+			return i
+		}
+	}
+	return last
+}
+
+// getLeftOut returns how many polls in a row each user of the given channel was left out
+func (p *KickerPlugin) getLeftOut(channelID string) map[string]int {
+	leftOut := map[string]int{}
+	data, appErr := p.API.KVGet(leftOutKey(channelID))
+	if appErr != nil {
+		p.API.LogError("failed to get left out counts", "channel_id", channelID, "err", appErr.Error())
+		return leftOut
+	}
+	if data != nil {
+		if err := json.Unmarshal(data, &leftOut); err != nil {
+			p.API.LogError("failed to parse left out counts", "channel_id", channelID, "err", err.Error())
+		}
+	}
+	return leftOut
+}
+
+// updateLeftOut counts another poll for the players left out on the waitlist, and resets the count of the chosen players
+func (p *KickerPlugin) updateLeftOut(channelID string, chosen, waitlist []Player) {
+	leftOut := p.getLeftOut(channelID)
+	for _, player := range chosen {
+		delete(leftOut, player.user.Id)
+	}
+	for _, player := range waitlist {
+		leftOut[player.user.Id]++
+	}
+
+	data, err := json.Marshal(leftOut)
+	if err != nil {
+		p.API.LogError("failed to serialize left out counts", "channel_id", channelID, "err", err.Error())
+		return
+	}
+	if appErr := p.API.KVSet(leftOutKey(channelID), data); appErr != nil {
+		p.API.LogError("failed to save left out counts", "channel_id", channelID, "err", appErr.Error())
+	}
+}
+
+// selectionWeights returns the weights for choosing the players of the given game, see fairnessWeights.
+// The game must be locked.
+func (p *KickerPlugin) selectionWeights(game *Game) map[string]float64 {
+	strength := p.getConfiguration().fairnessStrength
+	if strength <= 0 {
+		return nil
+	}
+
+	matches, appErr := p.getRecentMatches(game.channelID, fairnessHistoryCount, time.Time{})
+	if appErr != nil {
+		p.API.LogError("failed to get match history", "channel_id", game.channelID, "err", appErr.Error())
+		matches = []*Match{}
+	}
+	return fairnessWeights(game.participants, matches, p.getLeftOut(game.channelID), strength)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFairnessWeights(t *testing.T) {
+	players := []Player{*horst, *baerbel, *etienne, *ingebork}
+	matches := []*Match{
+		{Teams: [2][]string{{"1"}, {"2"}}},
+		{Teams: [2][]string{{"1"}, {"3"}}},
+	}
+	leftOut := map[string]int{"4": 2}
+
+	if weights := fairnessWeights(players, matches, leftOut, 0); weights != nil {
+		t.Errorf("Weights without strength should be nil, got %v", weights)
+	}
+
+	weights := fairnessWeights(players, matches, leftOut, 1)
+	// horst played twice, bärbel in the newest match, etienne in the older one, ingebork was left out twice
+	if !(weights["1"] < weights["2"] && weights["2"] < weights["3"] && weights["3"] < weights["4"]) {
+		t.Errorf("Weights should prefer players, who played less and less recently: %v", weights)
+	}
+
+	stronger := fairnessWeights(players, matches, leftOut, 2)
+	if stronger["4"]/stronger["1"] <= weights["4"]/weights["1"] {
+		t.Errorf("A higher strength should increase the preference: %v, %v", weights, stronger)
+	}
+}
+
+func TestChoosePlayersWeighted(t *testing.T) {
+	// players with a weight of 0 are only chosen, if there is no one else
+	weights := map[string]float64{"1": 0, "2": 0, "3": 1, "4": 1, "5": 1, "6": 1, "7": 0}
+
+	for i := 0; i < 20; i++ {
+		players := SetupTestGame([]Player{*horst, *baerbel, *etienne, *ingebork, *kay}).ChoosePlayers(2, weights)
+		if !playerEqual(players, []Player{*etienne, *ingebork}) {
+			t.Fatalf("Wrong participants were chosen: %v", playerIDs(players))
+		}
+
+		// the participants are still preferred over volunteers with a higher weight
+		players = SetupTestGame([]Player{*horst, *baerbel, *kay, *oke, *mable}).ChoosePlayers(4, weights)
+		if !playerEqual(players, []Player{*horst, *baerbel, *kay, *oke}) {
+			t.Fatalf("Wrong players were chosen: %v", playerIDs(players))
+		}
+	}
+}
+
+func TestFairnessWeightsOverflow(t *testing.T) {
+	players := []Player{*horst, *baerbel, *etienne, *ingebork}
+	leftOut := map[string]int{"3": 1000, "4": 2000}
+
+	weights := fairnessWeights(players, []*Match{}, leftOut, maxFairnessStrength)
+	for userID, weight := range weights {
+		if math.IsInf(weight, 0) || math.IsNaN(weight) || weight < 0 || weight > 1 {
+			t.Errorf("Weight of %s should be between 0 and 1, got: %v", userID, weight)
+		}
+	}
+	if weights["4"] != 1 {
+		t.Errorf("Player left out most often should get the highest weight, got: %v", weights)
+	}
+
+	for i := 0; i < 20; i++ {
+		chosen := SetupTestGame(players).ChoosePlayers(1, weights)
+		if len(chosen) != 1 || chosen[0].user.Id != "4" {
+			t.Fatalf("Player left out most often should be chosen, got: %v", playerIDs(chosen))
+		}
+	}
+
+	// weights, which do not sum up to a finite positive number, give everybody the same chance
+	for _, invalid := range []float64{math.Inf(1), math.NaN(), 0} {
+		weights = map[string]float64{"1": invalid, "2": invalid, "3": invalid, "4": invalid}
+		counts := map[int]int{}
+		for i := 0; i < 200; i++ {
+			counts[weightedIndex(players, weights)]++
+		}
+		if len(counts) != len(players) {
+			t.Errorf("Every player should be chosen with weights %v, got: %v", invalid, counts)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxTeamSize limits the team size of custom formats, so that all team splits can be compared
+	maxTeamSize = maxBalancedPlayers / 2
+	// formatOption is the option of the start command, which sets the format of a single game
+	formatOption = "--format"
+)
+
+// formatPattern matches game formats like "1v1", "2vs2" or "3 on 3"
+var formatPattern = regexp.MustCompile(`^(\d+)\s*(?:v|vs|on|x|:)\s*(\d+)$`)
+
+// parseFormat parses a game format like "1v1" or "2v2" and returns the number of players per team.
+// Both teams must have the same size, between 1 and maxTeamSize.
+func parseFormat(format string) (int, bool) {
+	matches := formatPattern.FindStringSubmatch(strings.ToLower(strings.TrimSpace(format)))
+	if matches == nil || matches[1] != matches[2] {
+		return 0, false
+	}
+
+	teamSize, _ := strconv.Atoi(matches[1])
+	if teamSize < 1 || teamSize > maxTeamSize {
+		return 0, false
+	}
+	return teamSize, true
+}
+
+// formatName returns the name of the format with the given team size, e.g. "2v2"
+func formatName(teamSize int) string {
+	return fmt.Sprintf("%dv%d", teamSize, teamSize)
+}
+
+// extractFormat removes the format option ("--format 1v1" or "--format=1v1") from the given arguments.
+// Returns the value of the option, whether it was given at all, and the remaining arguments.
+func extractFormat(params []string) (string, bool, []string) {
+	rest := []string{}
+	format, found := "", false
+	for i := 0; i < len(params); i++ {
+		switch {
+		case strings.ToLower(params[i]) == formatOption:
+			found = true
+			if i+1 < len(params) {
+				format = params[i+1]
+				i++
+			}
+		case strings.HasPrefix(strings.ToLower(params[i]), formatOption+"="):
+			found = true
+			format = params[i][len(formatOption)+1:]
+		default:
+			rest = append(rest, params[i])
+		}
+	}
+	return format, found, rest
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseFormat(t *testing.T) {
+	tables := []struct {
+		Format   string
+		TeamSize int
+		OK       bool
+	}{
+		{Format: "1v1", TeamSize: 1, OK: true},
+		{Format: "2v2", TeamSize: 2, OK: true},
+		{Format: "3vs3", TeamSize: 3, OK: true},
+		{Format: "2 on 2", TeamSize: 2, OK: true},
+		{Format: "1V1", TeamSize: 1, OK: true},
+		{Format: "2v1"},
+		{Format: "0v0"},
+		{Format: "9v9"},
+		{Format: "doubles"},
+		{Format: ""},
+	}
+
+	for _, table := range tables {
+		teamSize, ok := parseFormat(table.Format)
+		if teamSize != table.TeamSize || ok != table.OK {
+			t.Errorf("Format %q was parsed incorrectly, got: %d %t, want: %d %t", table.Format, teamSize, ok, table.TeamSize, table.OK)
+		}
+	}
+
+	if name := formatName(3); name != "3v3" {
+		t.Errorf("Format name was incorrect, got: %s", name)
+	}
+}
+
+func TestExtractFormat(t *testing.T) {
+	tables := []struct {
+		Args   string
+		Format string
+		Found  bool
+		Rest   []string
+	}{
+		{Args: "12 30", Rest: []string{"12", "30"}},
+		{Args: "--format 1v1 12:30", Format: "1v1", Found: true, Rest: []string{"12:30"}},
+		{Args: "in 20m --format=3v3", Format: "3v3", Found: true, Rest: []string{"in", "20m"}},
+		{Args: "now --format", Found: true, Rest: []string{"now"}},
+	}
+
+	for _, table := range tables {
+		format, found, rest := extractFormat(strings.Fields(table.Args))
+		if format != table.Format || found != table.Found || !reflect.DeepEqual(rest, table.Rest) {
+			t.Errorf("Format option of %q was extracted incorrectly, got: %q %t %v", table.Args, format, found, rest)
+		}
+	}
+}
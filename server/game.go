@@ -0,0 +1,200 @@
+package main
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// errGameEnded is returned when a game is changed after it was resolved or canceled
+var errGameEnded = errors.New("game has already ended")
+
+// Game holds the state of a single kicker poll, running in one channel.
+// Clicks, commands and timers change a game from different goroutines, so all fields
+// except the IDs set by NewGame must only be accessed while holding lock.
+type Game struct {
+	lock sync.Mutex
+	// ended is set when the poll was resolved or canceled, after which the game must not change anymore
+	ended bool
+
+	id           string // identifies the game in the context of its buttons
+	pollPost     *model.Post
+	cancelPost   *model.Post
+	endTime      time.Time // in UTC
+	timer        *time.Timer
+	timerWarning *time.Timer
+	timerRemind  *time.Timer
+	userID       string // user-ID of user who started the game
+	channelID    string
+	rootID       string
+	locale       string // language of the public posts
+	teamSize     int    // number of players per team, see parseFormat
+	warned       bool   // whether the warning about missing players was posted for the current endTime
+
+	participants []Player
+}
+
+// NewGame creates a game for the given channel, started by the given user
+func NewGame(userID, channelID, rootID string) *Game {
+	return &Game{
+		id:           model.NewId(),
+		userID:       userID,
+		channelID:    channelID,
+		rootID:       rootID,
+		participants: []Player{},
+	}
+}
+
+// end marks the game as ended and stops its timers. Returns false if it had already ended,
+// so that only the first of concurrent cancels and timers resolves the game.
+func (g *Game) end() bool {
+	if g.ended {
+		return false
+	}
+	g.ended = true
+	g.stopTimers()
+	return true
+}
+
+// playerCount returns the number of players needed for the game
+func (g *Game) playerCount() int {
+	return 2 * g.teamSize
+}
+
+// stopTimers stops the end, warning and reminder timer of the game, if set
+func (g *Game) stopTimers() {
+	if g.timer != nil {
+		g.timer.Stop()
+	}
+	if g.timerWarning != nil {
+		g.timerWarning.Stop()
+	}
+	if g.timerRemind != nil {
+		g.timerRemind.Stop()
+	}
+}
+
+func (g *Game) setPlayer(user *model.User, wantLevel WantLevel) {
+	g.removeParticipantByID(user.Id)
+	g.participants = append(g.participants, Player{
+		user:      user,
+		wantLevel: wantLevel,
+	})
+}
+
+// setAutomaticPlayer sets the standing answer of the user, see Prefs. Answering the poll replaces it.
+func (g *Game) setAutomaticPlayer(user *model.User, wantLevel WantLevel) {
+	g.setPlayer(user, wantLevel)
+	g.participants[len(g.participants)-1].automatic = true
+}
+
+func (g *Game) removeParticipantByID(id string) {
+	var participants []Player
+	for _, participant := range g.participants {
+		if id != participant.user.Id {
+			participants = append(participants, participant)
+		}
+	}
+	g.participants = participants
+}
+
+// ChoosePlayers returns playerCount random Player (if possible), chosen with the given weights per user-ID
+// (see fairnessWeights), or with equal chances if weights is nil.
+// Participants are prefered over Volunteers.
+func (g *Game) ChoosePlayers(playerCount int, weights map[string]float64) []Player {
+	var returnPlayer []Player
+	participants := g.GetParticipants()
+	volunteers := g.GetVolunteers()
+
+	if len(participants)+len(volunteers) < playerCount {
+		// not enough players! return all that wanted to play
+		return append(participants, volunteers...)
+	}
+
+	// generate seed depending on server-time
+	rand.Seed(time.Now().UnixNano())
+
+	if len(participants) >= playerCount {
+		// enough participants
+		for i := 0; i < playerCount; i++ {
+			// add random participants
+			randIndex := weightedIndex(participants, weights)
+			returnPlayer = append(returnPlayer, participants[randIndex])
+			participants = remove(participants, randIndex)
+		}
+	} else {
+		// not enough participants
+		// take all participants
+		returnPlayer = append(returnPlayer, participants...)
+		// add random volunteers
+		restPlayerCount := playerCount - len(returnPlayer)
+		for i := 0; i < restPlayerCount; i++ {
+			randIndex := weightedIndex(volunteers, weights)
+			returnPlayer = append(returnPlayer, volunteers[randIndex])
+			volunteers = remove(volunteers, randIndex)
+		}
+
+	}
+	return returnPlayer
+}
+
+// ChooseLineup chooses the players for as many full matches as possible, but at most one per table,
+// using the given weights like ChoosePlayers.
+// Returns the chosen players and the waitlist of all other players, who wanted to play:
+// participants before volunteers, each in the order they answered.
+func (g *Game) ChooseLineup(tables int, weights map[string]float64) ([]Player, []Player) {
+	candidates := append(g.GetParticipants(), g.GetVolunteers()...)
+	matchCount := len(candidates) / g.playerCount()
+	if matchCount > tables {
+		matchCount = tables
+	}
+
+	chosen := g.ChoosePlayers(matchCount*g.playerCount(), weights)
+	waitlist := []Player{}
+	for _, player := range candidates {
+		if !containsPlayer(chosen, player.user.Id) {
+			waitlist = append(waitlist, player)
+		}
+	}
+	return chosen, waitlist
+}
+
+// containsPlayer checks if the player with the given user-ID is one of the given players
+func containsPlayer(players []Player, userID string) bool {
+	for _, player := range players {
+		if player.user.Id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// GetParticipants returns all Players with the "participant" want level
+func (g *Game) GetParticipants() []Player {
+	return g.filterParticipantsByWantlevel(WLParticipate)
+}
+
+// GetVolunteers returns all Players with the "volunteer" want level
+func (g *Game) GetVolunteers() []Player {
+	return g.filterParticipantsByWantlevel(WLVolunteer)
+}
+
+// GetDecliners returns all Players with the "decline" want level
+func (g *Game) GetDecliners() []Player {
+	return g.filterParticipantsByWantlevel(WLDecline)
+}
+
+func (g *Game) filterParticipantsByWantlevel(wantLevel WantLevel) []Player {
+	var players []Player
+
+	for _, player := range g.participants {
+		if player.wantLevel == wantLevel {
+			players = append(players, player)
+		}
+	}
+
+	return players
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// startTestGame registers a running game in the given channel, ending after the given duration
+func startTestGame(p *KickerPlugin, channelID string, duration time.Duration) *Game {
+	game := NewGame("creator", channelID, "")
+	game.endTime = time.Now().Add(duration)
+	game.locale = "en"
+	game.teamSize = 2
+	game.pollPost = &model.Post{Id: "poll-" + channelID, ChannelId: channelID}
+	game.cancelPost = &model.Post{Id: "cancel-" + channelID}
+
+	game.lock.Lock()
+	defer game.lock.Unlock()
+	p.addGame(game)
+	p.startTimers(game)
+	return game
+}
+
+// countEndPosts returns the number of posts, which announce the end of a poll
+func countEndPosts(messages []string) int {
+	count := 0
+	for _, message := range messages {
+		if strings.HasPrefix(message, "Players: ") || message == "Not enough players!" || strings.HasSuffix(message, " stopped the bot!") {
+			count++
+		}
+	}
+	return count
+}
+
+func TestConcurrentClicksAndPollEnd(t *testing.T) {
+	translations := loadTestTranslations(t)
+
+	for round := 0; round < 20; round++ {
+		api := newFakeAPI()
+		p := newTestPlugin(api, translations)
+		game := startTestGame(p, "channel", time.Duration(round)*time.Millisecond)
+
+		var wg sync.WaitGroup
+		var acceptedLock sync.Mutex
+		accepted := map[string]bool{}
+
+		for i := 0; i < 40; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				userID := fmt.Sprintf("%d", i)
+				err := p.setUserWantLevel(game, userID, WantLevel(i%3-1))
+				if err == nil {
+					acceptedLock.Lock()
+					accepted[userID] = true
+					acceptedLock.Unlock()
+				} else if err != errGameEnded {
+					t.Errorf("Click of user %s failed: %s", userID, err)
+				}
+			}(i)
+		}
+		for i := 0; i < 5; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				p.CheckEnoughPlayer(game)
+			}()
+			go func() {
+				defer wg.Done()
+				p.CreateEndPollPost(game)
+			}()
+		}
+		wg.Wait()
+
+		if count := countEndPosts(api.createdPosts()); count != 1 {
+			t.Errorf("Round %d: poll should end exactly once, got %d end posts: %v", round, count, api.createdPosts())
+		}
+
+		if p.getGame("channel") != nil {
+			t.Errorf("Round %d: ended game is still registered", round)
+		}
+
+		if data, _ := api.KVGet(gameKey("channel")); data != nil {
+			t.Errorf("Round %d: ended game is still stored", round)
+		}
+
+		game.lock.Lock()
+		if len(game.participants) != len(accepted) {
+			t.Errorf("Round %d: %d clicks were accepted, but the game has %d participants", round, len(accepted), len(game.participants))
+		}
+		for _, player := range game.participants {
+			if !accepted[player.user.Id] {
+				t.Errorf("Round %d: rejected click of user %s changed the game", round, player.user.Id)
+			}
+		}
+		game.lock.Unlock()
+	}
+}
+
+func TestConcurrentCancelAndPollEnd(t *testing.T) {
+	translations := loadTestTranslations(t)
+
+	for round := 0; round < 20; round++ {
+		api := newFakeAPI()
+		p := newTestPlugin(api, translations)
+		game := startTestGame(p, "channel", time.Duration(round%3)*time.Millisecond)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 3; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				p.cancelGame(game, game.userID)
+			}()
+			go func() {
+				defer wg.Done()
+				p.CreateEndPollPost(game)
+			}()
+		}
+		wg.Wait()
+
+		if count := countEndPosts(api.createdPosts()); count != 1 {
+			t.Errorf("Round %d: game should be canceled or resolved exactly once, got %d end posts: %v", round, count, api.createdPosts())
+		}
+
+		if err := p.setUserWantLevel(game, "late", WLParticipate); err != errGameEnded {
+			t.Errorf("Round %d: click after the end should be rejected, got: %v", round, err)
+		}
+	}
+}
+
+func TestPollEndWithTables(t *testing.T) {
+	api := newFakeAPI()
+	p := newTestPlugin(api, loadTestTranslations(t))
+	c := defaultConfiguration()
+	c.Tables = "2"
+	if err := c.process(); err != nil {
+		t.Fatalf("Configuration was rejected: %s", err)
+	}
+	p.setConfiguration(c)
+
+	game := startTestGame(p, "channel", time.Hour)
+	for i := 1; i <= 10; i++ {
+		if err := p.setUserWantLevel(game, fmt.Sprintf("%d", i), WLParticipate); err != nil {
+			t.Fatalf("Click failed: %s", err)
+		}
+	}
+	p.CreateEndPollPost(game)
+
+	posts := api.createdPosts()
+	if len(posts) != 1 {
+		t.Fatalf("Poll end should create one post, got: %v", posts)
+	}
+	for _, expected := range []string{"\nTable 1: **Team A**", "\nTable 2: **Team A**", "\nWaitlist: "} {
+		if !strings.Contains(posts[0], expected) {
+			t.Errorf("Result post should contain %q, got: %s", expected, posts[0])
+		}
+	}
+
+	matchIDs, _ := p.getChannelMatchIDs("channel")
+	if len(matchIDs) != 2 {
+		t.Errorf("Both matches should be stored, got: %v", matchIDs)
+	}
+
+	leftOut := p.getLeftOut("channel")
+	if len(leftOut) != 2 {
+		t.Errorf("The two players on the waitlist should be counted as left out, got: %v", leftOut)
+	}
+	for userID, count := range leftOut {
+		if count != 1 {
+			t.Errorf("Player %s should be left out once, got: %d", userID, count)
+		}
+	}
+}
+
+func TestWarningDisabled(t *testing.T) {
+	p := newTestPlugin(newFakeAPI(), loadTestTranslations(t))
+	c := defaultConfiguration()
+	c.WarnMinutes = "0"
+	if err := c.process(); err != nil {
+		t.Fatalf("Configuration was rejected: %s", err)
+	}
+	p.setConfiguration(c)
+
+	game := startTestGame(p, "channel", time.Hour)
+	defer p.cancelGame(game, game.userID)
+
+	game.lock.Lock()
+	defer game.lock.Unlock()
+	if game.timerWarning != nil {
+		t.Errorf("No warning should be scheduled")
+	}
+	if text := p.statusText(game, time.Now(), p.translations.translator("en")); !strings.Contains(text, "warning about missing players is turned off") {
+		t.Errorf("Status should tell that the warning is off, got: %s", text)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const (
+	// fallbackLocale is used for messages, which are not translated into the requested locale
+	fallbackLocale = "en"
+	// channelLocaleKeyPrefix prefixes the KV store keys of the channel languages, followed by the channel-ID
+	channelLocaleKeyPrefix = "locale_"
+)
+
+// translateFunc returns the message with the given ID in a fixed locale, formatted with the given arguments
+type translateFunc func(id string, args ...interface{}) string
+
+// translationBundle holds the messages per locale and message ID
+type translationBundle map[string]map[string]string
+
+// loadTranslationBundle reads the messages from one JSON file per locale in the given directory,
+// e.g. de.json for the locale "de"
+func loadTranslationBundle(dir string) (translationBundle, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := translationBundle{}
+	for _, file := range files {
+		data, readErr := ioutil.ReadFile(file)
+		if readErr != nil {
+			return nil, readErr
+		}
+
+		messages := map[string]string{}
+		if jsonErr := json.Unmarshal(data, &messages); jsonErr != nil {
+			return nil, fmt.Errorf("failed to parse %s: %s", file, jsonErr)
+		}
+		bundle[strings.TrimSuffix(filepath.Base(file), ".json")] = messages
+	}
+
+	if _, ok := bundle[fallbackLocale]; !ok {
+		return nil, fmt.Errorf("translations for %s are missing in %s", fallbackLocale, dir)
+	}
+	return bundle, nil
+}
+
+// locales returns the available locales, sorted
+func (b translationBundle) locales() []string {
+	locales := []string{}
+	for locale := range b {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// supportedLocale returns the available locale matching the given Mattermost locale (e.g. "de" for "de-DE"),
+// or an empty string
+func (b translationBundle) supportedLocale(locale string) string {
+	locale = strings.ToLower(locale)
+	if _, ok := b[locale]; ok {
+		return locale
+	}
+	if i := strings.IndexAny(locale, "-_"); i > 0 {
+		if _, ok := b[locale[:i]]; ok {
+			return locale[:i]
+		}
+	}
+	return ""
+}
+
+// translate returns the message with the given ID in the given locale, falling back to fallbackLocale
+func (b translationBundle) translate(locale, id string, args ...interface{}) string {
+	message, ok := b[b.supportedLocale(locale)][id]
+	if !ok {
+		if message, ok = b[fallbackLocale][id]; !ok {
+			return id
+		}
+	}
+
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// translator returns a translateFunc for the given locale
+func (b translationBundle) translator(locale string) translateFunc {
+	return func(id string, args ...interface{}) string {
+		return b.translate(locale, id, args...)
+	}
+}
+
+func channelLocaleKey(channelID string) string {
+	return channelLocaleKeyPrefix + channelID
+}
+
+// defaultLocale returns the language for public posts in channels without own language:
+// the configured locale, or the default locale of the Mattermost server
+func (p *KickerPlugin) defaultLocale() string {
+	if locale := p.getConfiguration().Locale; locale != "" {
+		return locale
+	}
+	if config := p.API.GetConfig(); config != nil && config.LocalizationSettings.DefaultServerLocale != nil {
+		return *config.LocalizationSettings.DefaultServerLocale
+	}
+	return fallbackLocale
+}
+
+// channelLocale returns the language of the public posts in the given channel
+func (p *KickerPlugin) channelLocale(channelID string) string {
+	data, appErr := p.API.KVGet(channelLocaleKey(channelID))
+	if appErr != nil {
+		p.API.LogError("failed to get channel locale", "channel_id", channelID, "err", appErr.Error())
+	}
+	if len(data) > 0 {
+		return string(data)
+	}
+	return p.defaultLocale()
+}
+
+// channelTranslator returns a translateFunc for the public posts in the given channel
+func (p *KickerPlugin) channelTranslator(channelID string) translateFunc {
+	return p.translations.translator(p.channelLocale(channelID))
+}
+
+// userTranslator returns a translateFunc for the Mattermost locale of the given user, used for ephemeral messages
+func (p *KickerPlugin) userTranslator(userID string) translateFunc {
+	user, appErr := p.API.GetUser(userID)
+	if appErr != nil {
+		return p.translations.translator(p.defaultLocale())
+	}
+	return p.translations.translator(user.Locale)
+}
+
+// executeLanguageCommand sets the language of the public posts in the channel, if the user is an admin
+func (p *KickerPlugin) executeLanguageCommand(args *model.CommandArgs, params []string) (*model.CommandResponse, *model.AppError) {
+	if !p.canManage(args.UserId, "", args.ChannelId) {
+		return ephemeralResponse(p.userTranslator(args.UserId)("command.language.not_allowed")), nil
+	}
+
+	locales := p.translations.locales()
+	if len(params) != 1 || p.translations.supportedLocale(params[0]) == "" {
+		tr := p.userTranslator(args.UserId)
+		return ephemeralResponse(tr("command.language.usage", "`"+strings.Join(locales, "`, `")+"`")), nil
+	}
+
+	locale := p.translations.supportedLocale(params[0])
+	if appErr := p.API.KVSet(channelLocaleKey(args.ChannelId), []byte(locale)); appErr != nil {
+		return nil, appErr
+	}
+
+	return ephemeralResponse(p.translations.translate(locale, "command.language.done")), nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// loadTestTranslations loads the translations shipped with the plugin
+func loadTestTranslations(t *testing.T) translationBundle {
+	bundle, err := loadTranslationBundle("../assets/i18n")
+	if err != nil {
+		t.Fatalf("Translations could not be loaded: %s", err)
+	}
+	return bundle
+}
+
+func TestTranslationsComplete(t *testing.T) {
+	bundle := loadTestTranslations(t)
+
+	for _, locale := range bundle.locales() {
+		for id := range bundle[fallbackLocale] {
+			if _, ok := bundle[locale][id]; !ok {
+				t.Errorf("Message %s is missing in %s", id, locale)
+			}
+		}
+		for id := range bundle[locale] {
+			if _, ok := bundle[fallbackLocale][id]; !ok {
+				t.Errorf("Message %s of %s is missing in %s", id, locale, fallbackLocale)
+			}
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	bundle := translationBundle{
+		"en": {"hello": "Hello %s", "bye": "Bye"},
+		"de": {"hello": "Hallo %s"},
+	}
+
+	tables := []struct {
+		Locale string
+		ID     string
+		Result string
+	}{
+		{Locale: "de", ID: "hello", Result: "Hallo horst"},
+		{Locale: "de-DE", ID: "hello", Result: "Hallo horst"},
+		{Locale: "en", ID: "hello", Result: "Hello horst"},
+		{Locale: "fr", ID: "hello", Result: "Hello horst"},
+		{Locale: "", ID: "hello", Result: "Hello horst"},
+		{Locale: "de", ID: "bye", Result: "Bye"},
+		{Locale: "de", ID: "unknown", Result: "unknown"},
+	}
+
+	for _, table := range tables {
+		var result string
+		if table.ID == "hello" {
+			result = bundle.translate(table.Locale, table.ID, "horst")
+		} else {
+			result = bundle.translate(table.Locale, table.ID)
+		}
+		if result != table.Result {
+			t.Errorf("Translation of %s in '%s' was incorrect, got: %s, want: %s", table.ID, table.Locale, result, table.Result)
+		}
+	}
+}
+
+func TestLanguageCommandPermission(t *testing.T) {
+	api := newFakeAPI()
+	p := newTestPlugin(api, loadTestTranslations(t))
+	api.admins["admin"] = true
+
+	response, _ := p.executeLanguageCommand(&model.CommandArgs{UserId: "1", ChannelId: "channel"}, []string{"de"})
+	if !strings.Contains(response.Text, "Only admins") {
+		t.Errorf("Users should not change the language, got: %s", response.Text)
+	}
+	if _, ok := api.kv[channelLocaleKey("channel")]; ok {
+		t.Errorf("Language should not be stored")
+	}
+
+	p.executeLanguageCommand(&model.CommandArgs{UserId: "admin", ChannelId: "channel"}, []string{"de"})
+	if string(api.kv[channelLocaleKey("channel")]) != "de" {
+		t.Errorf("Admins should change the language, got: %s", api.kv[channelLocaleKey("channel")])
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// leaderboardLastPostKey is the KV store key of the time the weekly leaderboard was last posted
+const leaderboardLastPostKey = "leaderboard_last_post"
+
+// leaderboardPeriods maps the periods of the leaderboard command to their duration, 0 meaning all time
+var leaderboardPeriods = map[string]time.Duration{
+	"week":  7 * 24 * time.Hour,
+	"month": 30 * 24 * time.Hour,
+	"all":   0,
+}
+
+// PlayerStats are the aggregated results of a player
+type PlayerStats struct {
+	UserID string
+	Rating float64
+	Wins   int
+	Games  int
+}
+
+// WinRatio returns the share of won games
+func (s *PlayerStats) WinRatio() float64 {
+	if s.Games == 0 {
+		return 0
+	}
+	return float64(s.Wins) / float64(s.Games)
+}
+
+// AggregateStats sums up wins and games per player of all reported matches, which started after since.
+// Short-handed matches are left out, as they are not rated either.
+func AggregateStats(matches []*Match, since time.Time) map[string]*PlayerStats {
+	stats := map[string]*PlayerStats{}
+	for _, match := range matches {
+		if !match.Reported || match.ShortHanded || match.StartTime.Before(since) {
+			continue
+		}
+		winner := match.Winner()
+		for i, team := range match.Teams {
+			for _, userID := range team {
+				s, ok := stats[userID]
+				if !ok {
+					s = &PlayerStats{UserID: userID, Rating: initialRating}
+					stats[userID] = s
+				}
+				s.Games++
+				if i == winner {
+					s.Wins++
+				}
+			}
+		}
+	}
+	return stats
+}
+
+// RankStats returns the stats ordered by wins, win ratio and games. With byRating, the rating comes first;
+// this only suits the all-time leaderboard, as the rating reflects all matches ever played.
+func RankStats(stats map[string]*PlayerStats, byRating bool) []*PlayerStats {
+	ranking := []*PlayerStats{}
+	for _, s := range stats {
+		ranking = append(ranking, s)
+	}
+
+	sort.Slice(ranking, func(i, j int) bool {
+		a, b := ranking[i], ranking[j]
+		if byRating && a.Rating != b.Rating {
+			return a.Rating > b.Rating
+		}
+		if a.Wins != b.Wins {
+			return a.Wins > b.Wins
+		}
+		if a.WinRatio() != b.WinRatio() {
+			return a.WinRatio() > b.WinRatio()
+		}
+		if a.Games != b.Games {
+			return a.Games > b.Games
+		}
+		if a.Rating != b.Rating {
+			return a.Rating > b.Rating
+		}
+		return a.UserID < b.UserID
+	})
+	return ranking
+}
+
+// buildLeaderboard returns the leaderboard of the matches in the given channel and period as Markdown table
+func (p *KickerPlugin) buildLeaderboard(channelID, period string, now time.Time, tr translateFunc) (string, *model.AppError) {
+	since := time.Time{}
+	duration := leaderboardPeriods[period]
+	if duration > 0 {
+		since = now.Add(-duration)
+	}
+
+	matches, appErr := p.getRecentMatches(channelID, math.MaxInt32, since)
+	if appErr != nil {
+		return "", appErr
+	}
+
+	stats := AggregateStats(matches, since)
+	if len(stats) == 0 {
+		return tr("leaderboard.empty"), nil
+	}
+
+	for userID, s := range stats {
+		rating, ratingErr := p.getRating(userID)
+		if ratingErr != nil {
+			return "", ratingErr
+		}
+		s.Rating = rating.Value
+	}
+
+	cache := map[string]string{}
+	text := "#### " + tr("leaderboard.title."+period) + "\n\n"
+	text += tr("leaderboard.header") + "\n"
+	text += "|--:|:--------|--------:|------:|-------:|------:|\n"
+	for i, s := range RankStats(stats, duration == 0) {
+		text += fmt.Sprintf("| %d | %s | %.0f | %d | %d | %.0f%% |\n", i+1, p.getUsernames([]string{s.UserID}, cache)[0], s.Rating, s.Wins, s.Games, s.WinRatio()*100)
+	}
+	return text, nil
+}
+
+// executeLeaderboardCommand shows the leaderboard of the given period
+func (p *KickerPlugin) executeLeaderboardCommand(args *model.CommandArgs, params []string) (*model.CommandResponse, *model.AppError) {
+	tr := p.userTranslator(args.UserId)
+	period := "all"
+	if len(params) > 0 {
+		period = params[0]
+	}
+	if _, ok := leaderboardPeriods[period]; !ok {
+		return ephemeralResponse(tr("leaderboard.usage")), nil
+	}
+
+	text, appErr := p.buildLeaderboard(args.ChannelId, period, time.Now(), tr)
+	if appErr != nil {
+		return nil, appErr
+	}
+	return ephemeralResponse(text), nil
+}
+
+// nextLeaderboardTime returns the first point in time after last, at the given weekday and hour
+func nextLeaderboardTime(last time.Time, weekday time.Weekday, hour int, loc *time.Location) time.Time {
+	last = last.In(loc)
+	next := time.Date(last.Year(), last.Month(), last.Day(), hour, 0, 0, 0, loc)
+	next = next.AddDate(0, 0, (int(weekday)-int(next.Weekday())+7)%7)
+	if !next.After(last) {
+		next = next.AddDate(0, 0, 7)
+	}
+	return next
+}
+
+// postWeeklyLeaderboard posts the leaderboard of the last week into the configured channel, if it is due
+func (p *KickerPlugin) postWeeklyLeaderboard(now time.Time) {
+	configuration := p.getConfiguration()
+	if configuration.LeaderboardChannelID == "" {
+		return
+	}
+
+	data, appErr := p.API.KVGet(leaderboardLastPostKey)
+	if appErr != nil {
+		p.API.LogError("failed to get last leaderboard post", "err", appErr.Error())
+		return
+	}
+
+	if data == nil {
+		// start counting from the first run, instead of posting right away
+		p.saveLastLeaderboardPost(now)
+		return
+	}
+
+	var last time.Time
+	if err := json.Unmarshal(data, &last); err != nil {
+		p.API.LogError("failed to parse last leaderboard post", "err", err.Error())
+		return
+	}
+
+	if nextLeaderboardTime(last, configuration.leaderboardWeekday, configuration.leaderboardHour, configuration.location).After(now) {
+		return
+	}
+
+	text, appErr := p.buildLeaderboard(configuration.LeaderboardChannelID, "week", now, p.channelTranslator(configuration.LeaderboardChannelID))
+	if appErr != nil {
+		p.API.LogError("failed to build leaderboard", "err", appErr.Error())
+		return
+	}
+
+	if _, appErr = p.API.CreatePost(&model.Post{
+		UserId:    p.botUserID,
+		ChannelId: configuration.LeaderboardChannelID,
+		Message:   text,
+		Type:      model.POST_DEFAULT,
+	}); appErr != nil {
+		p.API.LogError("failed to post leaderboard", "channel_id", configuration.LeaderboardChannelID, "err", appErr.Error())
+		return
+	}
+
+	p.saveLastLeaderboardPost(now)
+}
+
+func (p *KickerPlugin) saveLastLeaderboardPost(last time.Time) {
+	data, err := json.Marshal(last)
+	if err != nil {
+		p.API.LogError("failed to serialize last leaderboard post", "err", err.Error())
+		return
+	}
+	if appErr := p.API.KVSet(leaderboardLastPostKey, data); appErr != nil {
+		p.API.LogError("failed to save last leaderboard post", "err", appErr.Error())
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAggregateStats(t *testing.T) {
+	now := time.Date(2019, 7, 12, 12, 0, 0, 0, time.UTC)
+	matches := []*Match{
+		{Teams: [2][]string{{"1", "2"}, {"3", "4"}}, Score: [2]int{10, 5}, Reported: true, StartTime: now.AddDate(0, 0, -1)},
+		{Teams: [2][]string{{"1", "3"}, {"2", "4"}}, Score: [2]int{3, 10}, Reported: true, StartTime: now.AddDate(0, 0, -2)},
+		// not reported
+		{Teams: [2][]string{{"1", "2"}, {"3", "4"}}, StartTime: now},
+		// too old
+		{Teams: [2][]string{{"1", "2"}, {"3", "4"}}, Score: [2]int{10, 5}, Reported: true, StartTime: now.AddDate(0, 0, -10)},
+		// short-handed
+		{Teams: [2][]string{{"1", "2"}, {"3"}}, Score: [2]int{5, 10}, Reported: true, ShortHanded: true, StartTime: now.AddDate(0, 0, -3)},
+	}
+
+	stats := AggregateStats(matches, now.AddDate(0, 0, -7))
+
+	expected := map[string][2]int{"1": {1, 2}, "2": {2, 2}, "3": {0, 2}, "4": {1, 2}}
+	for userID, e := range expected {
+		if stats[userID].Wins != e[0] || stats[userID].Games != e[1] {
+			t.Errorf("Stats of user %s were incorrect, got: %d/%d, want: %d/%d", userID, stats[userID].Wins, stats[userID].Games, e[0], e[1])
+		}
+	}
+
+	ranking := RankStats(stats, false)
+	order := []string{"2", "1", "4", "3"}
+	for i, userID := range order {
+		if ranking[i].UserID != userID {
+			t.Errorf("Rank %d was incorrect, got: %s, want: %s", i+1, ranking[i].UserID, userID)
+		}
+	}
+}
+
+func TestRankStatsByRating(t *testing.T) {
+	stats := map[string]*PlayerStats{
+		"1": {UserID: "1", Rating: 1000, Wins: 5, Games: 5},
+		"2": {UserID: "2", Rating: 1100, Wins: 1, Games: 5},
+	}
+
+	ranking := RankStats(stats, true)
+	if ranking[0].UserID != "2" {
+		t.Errorf("Higher rating should rank first, got: %s", ranking[0].UserID)
+	}
+
+	// within a period, the all-time rating must not outweigh the results of the period
+	ranking = RankStats(stats, false)
+	if ranking[0].UserID != "1" {
+		t.Errorf("More wins should rank first in a period, got: %s", ranking[0].UserID)
+	}
+}
+
+func TestBuildLeaderboardPerChannel(t *testing.T) {
+	api := newFakeAPI()
+	p := newTestPlugin(api, loadTestTranslations(t))
+	now := time.Now()
+
+	for _, channelID := range []string{"channel", "other"} {
+		teams := [2][]Player{{*horst}, {*baerbel}}
+		if channelID == "other" {
+			teams = [2][]Player{{*etienne}, {*ingebork}}
+		}
+		match := NewMatch(channelID, teams, now.Add(-time.Hour))
+		match.Score, match.Reported = [2]int{10, 5}, true
+		if appErr := p.addMatch(match); appErr != nil {
+			t.Fatalf("Failed to store match: %s", appErr.Error())
+		}
+	}
+
+	text, appErr := p.buildLeaderboard("channel", "week", now, p.translations.translator("en"))
+	if appErr != nil {
+		t.Fatalf("Failed to build leaderboard: %s", appErr.Error())
+	}
+	if !strings.Contains(text, "user1") || !strings.Contains(text, "user2") || strings.Contains(text, "user3") {
+		t.Errorf("Leaderboard should only contain the matches of the channel, got: %s", text)
+	}
+}
+
+func TestNextLeaderboardTime(t *testing.T) {
+	loc, _ := time.LoadLocation("Europe/Berlin")
+
+	tables := []struct {
+		Last   time.Time
+		Result time.Time
+	}{
+		// Wednesday -> Friday of the same week
+		{
+			Last:   time.Date(2019, 7, 10, 9, 0, 0, 0, loc),
+			Result: time.Date(2019, 7, 12, 16, 0, 0, 0, loc),
+		},
+		// Friday before the hour -> same day
+		{
+			Last:   time.Date(2019, 7, 12, 15, 59, 0, 0, loc),
+			Result: time.Date(2019, 7, 12, 16, 0, 0, 0, loc),
+		},
+		// Friday at the hour -> next week
+		{
+			Last:   time.Date(2019, 7, 12, 16, 0, 0, 0, loc),
+			Result: time.Date(2019, 7, 19, 16, 0, 0, 0, loc),
+		},
+	}
+
+	for _, table := range tables {
+		next := nextLeaderboardTime(table.Last, time.Friday, 16, loc)
+		if !next.Equal(table.Result) {
+			t.Errorf("Next leaderboard time after %s was incorrect, got: %s, want: %s", table.Last, next, table.Result)
+		}
+	}
+}
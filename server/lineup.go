@@ -0,0 +1,273 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// lineupKeyPrefix prefixes the KV store keys of lineups, followed by the lineup-ID
+const lineupKeyPrefix = "lineup_"
+
+// errNotInLineup is returned when a user drops out of a lineup, in which the user neither plays nor waits
+var errNotInLineup = errors.New("user is not part of the lineup")
+
+// Lineup is the outcome of a poll, as shown in its result post: the matches formed,
+// and the players waiting to move up if a chosen player drops out
+type Lineup struct {
+	ID        string   `json:"id"`
+	PostID    string   `json:"post_id"`
+	ChannelID string   `json:"channel_id"`
+	RootID    string   `json:"root_id"`
+	Locale    string   `json:"locale"`
+	MatchIDs  []string `json:"match_ids"`
+	Waitlist  []string `json:"waitlist"` // user-IDs, the next substitute first
+}
+
+func lineupKey(lineupID string) string {
+	return lineupKeyPrefix + lineupID
+}
+
+// removeFromWaitlist removes the given user from the waitlist. Returns false if the user was not waiting.
+func (l *Lineup) removeFromWaitlist(userID string) bool {
+	for i, id := range l.Waitlist {
+		if id == userID {
+			l.Waitlist = append(l.Waitlist[:i], l.Waitlist[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// substitute replaces the given user in the given match by the first player of the waitlist, on the same position.
+// Returns the user-ID of the substitute, or an empty string if nobody is waiting and the user was only removed.
+func (l *Lineup) substitute(match *Match, userID string) string {
+	substituteID := ""
+	if len(l.Waitlist) > 0 {
+		substituteID = l.Waitlist[0]
+		l.Waitlist = l.Waitlist[1:]
+	}
+
+	for i, team := range match.Teams {
+		for j, id := range team {
+			if id != userID {
+				continue
+			}
+			if substituteID != "" {
+				match.Teams[i][j] = substituteID
+			} else {
+				match.Teams[i] = append(team[:j:j], team[j+1:]...)
+			}
+			return substituteID
+		}
+	}
+	return substituteID
+}
+
+// saveLineup writes the given lineup to the KV store
+func (p *KickerPlugin) saveLineup(lineup *Lineup) *model.AppError {
+	data, err := json.Marshal(lineup)
+	if err != nil {
+		return appError("failed to serialize lineup", err)
+	}
+	return p.API.KVSet(lineupKey(lineup.ID), data)
+}
+
+// getLineup reads the lineup with the given ID from the KV store
+func (p *KickerPlugin) getLineup(lineupID string) (*Lineup, *model.AppError) {
+	data, appErr := p.API.KVGet(lineupKey(lineupID))
+	if appErr != nil {
+		return nil, appErr
+	}
+	if data == nil {
+		return nil, appError("lineup not found", nil)
+	}
+
+	var lineup Lineup
+	if err := json.Unmarshal(data, &lineup); err != nil {
+		return nil, appError("failed to parse lineup", err)
+	}
+	return &lineup, nil
+}
+
+// getLineupMatches reads the matches of the given lineup from the KV store
+func (p *KickerPlugin) getLineupMatches(lineup *Lineup) ([]*Match, *model.AppError) {
+	matches := []*Match{}
+	for _, matchID := range lineup.MatchIDs {
+		match, appErr := p.getMatch(matchID)
+		if appErr != nil {
+			return nil, appErr
+		}
+		matches = append(matches, match)
+	}
+	return matches, nil
+}
+
+// getPlayers returns the players with the given user-IDs, using the cache of already fetched users
+func (p *KickerPlugin) getPlayers(userIDs []string, cache map[string]*model.User) []Player {
+	players := []Player{}
+	for _, userID := range userIDs {
+		user, ok := cache[userID]
+		if !ok {
+			var appErr *model.AppError
+			if user, appErr = p.API.GetUser(userID); appErr != nil {
+				user = &model.User{Id: userID, Username: "?"}
+			}
+			cache[userID] = user
+		}
+		players = append(players, Player{user: user, wantLevel: WLParticipate})
+	}
+	return players
+}
+
+// lineupPost returns the result post showing the given lineup, with the button to drop out
+func (p *KickerPlugin) lineupPost(lineup *Lineup, matches []*Match) *model.Post {
+	tr := p.translations.translator(lineup.Locale)
+	cache := map[string]*model.User{}
+
+	userIDs := []string{}
+	for _, match := range matches {
+		userIDs = append(userIDs, match.Players()...)
+	}
+	ratings, appErr := p.getRatings(userIDs)
+	if appErr != nil {
+		p.API.LogError("failed to get ratings", "channel_id", lineup.ChannelID, "err", appErr.Error())
+	}
+
+	lines := []string{tr("game.players", JoinPlayerNames(p.getPlayers(userIDs, cache)))}
+	for i, match := range matches {
+		teams := [2][]Player{p.getPlayers(match.Teams[0], cache), p.getPlayers(match.Teams[1], cache)}
+		pairing := FormatPairing(teams, ratings, tr)
+		if len(matches) > 1 {
+			pairing = tr("game.table", i+1, pairing)
+		}
+		lines = append(lines, pairing)
+	}
+	if len(lineup.Waitlist) > 0 {
+		lines = append(lines, tr("game.waitlist", JoinPlayerNames(p.getPlayers(lineup.Waitlist, cache))))
+	}
+	lines = append(lines, tr("game.result_hint", p.getConfiguration().Trigger))
+
+	post := &model.Post{
+		Id:        lineup.PostID,
+		UserId:    p.botUserID,
+		ChannelId: lineup.ChannelID,
+		Message:   strings.Join(lines, "\n"),
+		RootId:    lineup.RootID,
+		Type:      model.POST_DEFAULT,
+	}
+	model.ParseSlackAttachment(post, []*model.SlackAttachment{{
+		Actions: []*model.PostAction{{
+			Name: tr("lineup.drop_out"),
+			Type: model.POST_ACTION_TYPE_BUTTON,
+			Integration: &model.PostActionIntegration{
+				URL:     fmt.Sprintf("%s/plugins/%s/drop-out", p.siteURL, manifest.ID),
+				Context: map[string]interface{}{"lineup_id": lineup.ID},
+			},
+		}},
+	}})
+	return post
+}
+
+// createLineup stores the given matches and waitlist, and posts them as result of the given game
+func (p *KickerPlugin) createLineup(game *Game, matches []*Match, waitlist []Player) {
+	lineup := &Lineup{
+		ID:        model.NewId(),
+		ChannelID: game.channelID,
+		RootID:    game.rootID,
+		Locale:    game.locale,
+		MatchIDs:  []string{},
+		Waitlist:  playerIDs(waitlist),
+	}
+	for _, match := range matches {
+		lineup.MatchIDs = append(lineup.MatchIDs, match.ID)
+	}
+
+	post, appErr := p.API.CreatePost(p.lineupPost(lineup, matches))
+	if appErr != nil {
+		p.API.LogError("failed to post lineup", "channel_id", game.channelID, "err", appErr.Error())
+		return
+	}
+
+	lineup.PostID = post.Id
+	if appErr = p.saveLineup(lineup); appErr != nil {
+		p.API.LogError("failed to save lineup", "channel_id", game.channelID, "err", appErr.Error())
+	}
+}
+
+// dropOut removes the given user from the given lineup and updates the result post. A chosen player is replaced
+// by the next player of the waitlist, who is mentioned in the thread and told by direct message. Without substitute,
+// the match is short-handed and will not be rated. Returns errNotInLineup if the user neither plays in a match
+// without result nor waits.
+func (p *KickerPlugin) dropOut(lineupID, userID string) error {
+	// the same lock as for reporting results, so that nobody drops out of a match while it is rated
+	p.matchesLock.Lock()
+	defer p.matchesLock.Unlock()
+
+	lineup, appErr := p.getLineup(lineupID)
+	if appErr != nil {
+		return appErr
+	}
+	matches, appErr := p.getLineupMatches(lineup)
+	if appErr != nil {
+		return appErr
+	}
+
+	var match *Match
+	for _, m := range matches {
+		if !m.Reported && m.HasPlayer(userID) {
+			match = m
+			break
+		}
+	}
+
+	message := ""
+	substituteID := ""
+	if match != nil {
+		tr := p.translations.translator(lineup.Locale)
+		cache := map[string]string{}
+		substituteID = lineup.substitute(match, userID)
+		if substituteID == "" {
+			match.ShortHanded = true
+		}
+		if appErr = p.saveMatch(match); appErr != nil {
+			return appErr
+		}
+		if substituteID != "" {
+			message = tr("lineup.substitute", p.getUsernames([]string{substituteID}, cache)[0], p.getUsernames([]string{userID}, cache)[0])
+		} else {
+			message = tr("lineup.no_substitute", p.getUsernames([]string{userID}, cache)[0])
+		}
+	} else if !lineup.removeFromWaitlist(userID) {
+		return errNotInLineup
+	}
+
+	if appErr = p.saveLineup(lineup); appErr != nil {
+		return appErr
+	}
+	if _, appErr = p.API.UpdatePost(p.lineupPost(lineup, matches)); appErr != nil {
+		p.API.LogError("failed to update lineup", "channel_id", lineup.ChannelID, "err", appErr.Error())
+	}
+
+	if message != "" {
+		// answer in the thread of the result post, or the thread the poll was started in
+		rootID := lineup.RootID
+		if rootID == "" {
+			rootID = lineup.PostID
+		}
+		p.API.CreatePost(&model.Post{
+			UserId:    p.botUserID,
+			ChannelId: lineup.ChannelID,
+			Message:   message,
+			RootId:    rootID,
+			Type:      model.POST_DEFAULT,
+		})
+	}
+	if substituteID != "" {
+		p.sendStartReminders(lineup.ChannelID, matches, map[string]bool{substituteID: true})
+	}
+	return nil
+}
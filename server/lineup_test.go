@@ -0,0 +1,40 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLineupSubstitute(t *testing.T) {
+	lineup := &Lineup{Waitlist: []string{"5", "6"}}
+	match := &Match{Teams: [2][]string{{"1", "2"}, {"3", "4"}}}
+
+	if substituteID := lineup.substitute(match, "2"); substituteID != "5" {
+		t.Errorf("First waiting player should move up, got: %s", substituteID)
+	}
+	if !reflect.DeepEqual(match.Teams, [2][]string{{"1", "5"}, {"3", "4"}}) {
+		t.Errorf("Substitute should take the position of the player, got: %v", match.Teams)
+	}
+	if !reflect.DeepEqual(lineup.Waitlist, []string{"6"}) {
+		t.Errorf("Substitute should leave the waitlist, got: %v", lineup.Waitlist)
+	}
+
+	lineup.substitute(match, "3")
+	if substituteID := lineup.substitute(match, "1"); substituteID != "" {
+		t.Errorf("Without waiting players there should be no substitute, got: %s", substituteID)
+	}
+	if !reflect.DeepEqual(match.Teams, [2][]string{{"5"}, {"6", "4"}}) {
+		t.Errorf("Player without substitute should be removed, got: %v", match.Teams)
+	}
+}
+
+func TestLineupRemoveFromWaitlist(t *testing.T) {
+	lineup := &Lineup{Waitlist: []string{"5", "6", "7"}}
+
+	if !lineup.removeFromWaitlist("6") || !reflect.DeepEqual(lineup.Waitlist, []string{"5", "7"}) {
+		t.Errorf("Player should be removed from the waitlist, got: %v", lineup.Waitlist)
+	}
+	if lineup.removeFromWaitlist("1") {
+		t.Errorf("Player, who is not waiting, can not be removed")
+	}
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const (
+	// matchKeyPrefix prefixes the KV store keys of matches, followed by the match-ID
+	matchKeyPrefix = "match_"
+	// channelMatchesKeyPrefix prefixes the KV store keys of the match-ID lists per channel, followed by the channel-ID
+	channelMatchesKeyPrefix = "channelmatches_"
+	// defaultHistoryCount is the number of matches listed by the history command, if no number is given
+	defaultHistoryCount = 5
+	// maxHistoryCount is the maximum number of matches listed by the history command
+	maxHistoryCount = 50
+)
+
+// Match is a game which took place, with its teams and (once reported) its result
+type Match struct {
+	ID          string      `json:"id"`
+	ChannelID   string      `json:"channel_id"`
+	Format      string      `json:"format"` // e.g. "2v2"
+	Teams       [2][]string `json:"teams"`  // user-IDs of the players per team
+	Score       [2]int      `json:"score"`
+	Reported    bool        `json:"reported"`
+	ShortHanded bool        `json:"short_handed,omitempty"` // a player dropped out without substitute, the match is not rated
+	StartTime   time.Time   `json:"start_time"`
+}
+
+// NewMatch creates a match for the given teams, starting at the given time
+func NewMatch(channelID string, teams [2][]Player, startTime time.Time) *Match {
+	match := &Match{
+		ID:        model.NewId(),
+		ChannelID: channelID,
+		Format:    formatName(len(teams[0])),
+		StartTime: startTime,
+	}
+	for i, team := range teams {
+		match.Teams[i] = []string{}
+		for _, player := range team {
+			match.Teams[i] = append(match.Teams[i], player.user.Id)
+		}
+	}
+	return match
+}
+
+// Players returns the user-IDs of all players of the match
+func (m *Match) Players() []string {
+	return append(append([]string{}, m.Teams[0]...), m.Teams[1]...)
+}
+
+// HasPlayer checks if the given user played in the match
+func (m *Match) HasPlayer(userID string) bool {
+	for _, id := range m.Players() {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// Winner returns the index of the winning team, or -1 if no result was reported
+func (m *Match) Winner() int {
+	if !m.Reported {
+		return -1
+	}
+	if m.Score[0] > m.Score[1] {
+		return 0
+	}
+	return 1
+}
+
+// splitTeams divides the chosen players into two teams of equal size
+func splitTeams(players []Player) [2][]Player {
+	half := len(players) / 2
+	return [2][]Player{players[:half], players[half:]}
+}
+
+// parseScore parses the goals of both teams, as given to the result command
+func parseScore(args []string) ([2]int, error) {
+	var score [2]int
+	if len(args) != 2 {
+		return score, fmt.Errorf("expected two scores, got %d", len(args))
+	}
+	for i, arg := range args {
+		goals, err := strconv.Atoi(arg)
+		if err != nil || goals < 0 {
+			return score, fmt.Errorf("invalid score %q", arg)
+		}
+		score[i] = goals
+	}
+	if score[0] == score[1] {
+		return score, fmt.Errorf("a match can not end in a draw")
+	}
+	return score, nil
+}
+
+func matchKey(matchID string) string {
+	return matchKeyPrefix + matchID
+}
+
+func channelMatchesKey(channelID string) string {
+	return channelMatchesKeyPrefix + channelID
+}
+
+// saveMatch writes the given match to the KV store
+func (p *KickerPlugin) saveMatch(match *Match) *model.AppError {
+	data, err := json.Marshal(match)
+	if err != nil {
+		return appError("failed to serialize match", err)
+	}
+	return p.API.KVSet(matchKey(match.ID), data)
+}
+
+// getMatch reads the match with the given ID from the KV store
+func (p *KickerPlugin) getMatch(matchID string) (*Match, *model.AppError) {
+	data, appErr := p.API.KVGet(matchKey(matchID))
+	if appErr != nil {
+		return nil, appErr
+	}
+	if data == nil {
+		return nil, appError("match not found", nil)
+	}
+
+	var match Match
+	if err := json.Unmarshal(data, &match); err != nil {
+		return nil, appError("failed to parse match", err)
+	}
+	return &match, nil
+}
+
+// getChannelMatchIDs returns the IDs of all matches of the given channel, oldest first
+func (p *KickerPlugin) getChannelMatchIDs(channelID string) ([]string, *model.AppError) {
+	data, appErr := p.API.KVGet(channelMatchesKey(channelID))
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	matchIDs := []string{}
+	if data == nil {
+		return matchIDs, nil
+	}
+	if err := json.Unmarshal(data, &matchIDs); err != nil {
+		return nil, appError("failed to parse match list", err)
+	}
+	return matchIDs, nil
+}
+
+// addMatch stores a new match, and appends it to the history of its channel
+func (p *KickerPlugin) addMatch(match *Match) *model.AppError {
+	if appErr := p.saveMatch(match); appErr != nil {
+		return appErr
+	}
+
+	p.matchesLock.Lock()
+	defer p.matchesLock.Unlock()
+
+	matchIDs, appErr := p.getChannelMatchIDs(match.ChannelID)
+	if appErr != nil {
+		return appErr
+	}
+
+	data, err := json.Marshal(append(matchIDs, match.ID))
+	if err != nil {
+		return appError("failed to serialize match list", err)
+	}
+	return p.API.KVSet(channelMatchesKey(match.ChannelID), data)
+}
+
+// getRecentMatches returns up to count matches of the given channel, which started after since, newest first.
+// The matches are read from the newest on, so the reading stops at the first older match.
+func (p *KickerPlugin) getRecentMatches(channelID string, count int, since time.Time) ([]*Match, *model.AppError) {
+	matchIDs, appErr := p.getChannelMatchIDs(channelID)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	matches := []*Match{}
+	for i := len(matchIDs) - 1; i >= 0 && len(matches) < count; i-- {
+		match, matchErr := p.getMatch(matchIDs[i])
+		if matchErr != nil {
+			p.API.LogError("failed to get match", "match_id", matchIDs[i], "err", matchErr.Error())
+			continue
+		}
+		if match.StartTime.Before(since) {
+			break
+		}
+		matches = append(matches, match)
+	}
+	return matches, nil
+}
+
+// getUsernames returns the usernames for the given user-IDs, using and filling the given cache
+func (p *KickerPlugin) getUsernames(userIDs []string, cache map[string]string) []string {
+	usernames := []string{}
+	for _, userID := range userIDs {
+		username, ok := cache[userID]
+		if !ok {
+			username = "?"
+			if user, appErr := p.API.GetUser(userID); appErr == nil {
+				username = user.Username
+			}
+			cache[userID] = username
+		}
+		usernames = append(usernames, username)
+	}
+	return usernames
+}
+
+// formatMatch returns a single line describing the teams and result of the given match
+func (p *KickerPlugin) formatMatch(match *Match, cache map[string]string) string {
+	teamA := strings.Join(p.getUsernames(match.Teams[0], cache), ", ")
+	teamB := strings.Join(p.getUsernames(match.Teams[1], cache), ", ")
+
+	result := "–:–"
+	if match.Reported {
+		result = fmt.Sprintf("%d:%d", match.Score[0], match.Score[1])
+	}
+
+	return fmt.Sprintf("%s vs %s %s", teamA, teamB, result)
+}
+
+// executeResultCommand reports the score of the latest match without result in the channel, which the user played
+func (p *KickerPlugin) executeResultCommand(args *model.CommandArgs, params []string) (*model.CommandResponse, *model.AppError) {
+	tr := p.userTranslator(args.UserId)
+	score, err := parseScore(params)
+	if err != nil {
+		return ephemeralResponse(tr("result.usage", p.getConfiguration().Trigger)), nil
+	}
+
+	// concurrent reports must not both pass the check for an existing result, and rate the match twice
+	p.matchesLock.Lock()
+	defer p.matchesLock.Unlock()
+
+	matches, appErr := p.getRecentMatches(args.ChannelId, maxHistoryCount, time.Time{})
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	// with several tables, multiple matches may wait for their result: take the latest match of the user,
+	// so that a report never lands on an older match
+	var match *Match
+	unreported := false
+	for _, m := range matches {
+		if !m.Reported {
+			unreported = true
+		}
+		if m.HasPlayer(args.UserId) {
+			match = m
+			break
+		}
+	}
+	switch {
+	case match != nil && match.Reported:
+		return ephemeralResponse(tr("result.already_reported", p.formatMatch(match, map[string]string{}))), nil
+	case !unreported:
+		return ephemeralResponse(tr("result.no_match")), nil
+	case match == nil:
+		return ephemeralResponse(tr("result.not_allowed")), nil
+	}
+
+	match.Score = score
+	match.Reported = true
+	if appErr = p.saveMatch(match); appErr != nil {
+		return nil, appErr
+	}
+	channelTr := p.channelTranslator(args.ChannelId)
+	message := channelTr("result.post", p.formatMatch(match, map[string]string{}))
+	if match.ShortHanded {
+		message += "\n" + channelTr("result.not_rated")
+	} else if appErr = p.updateRatings(match); appErr != nil {
+		p.API.LogError("failed to update ratings", "match_id", match.ID, "err", appErr.Error())
+	}
+
+	p.API.CreatePost(&model.Post{
+		UserId:    p.botUserID,
+		ChannelId: args.ChannelId,
+		Message:   message,
+		RootId:    args.RootId,
+		Type:      model.POST_DEFAULT,
+	})
+
+	return ephemeralResponse(""), nil
+}
+
+// executeHistoryCommand lists the recent matches of the channel
+func (p *KickerPlugin) executeHistoryCommand(args *model.CommandArgs, params []string) (*model.CommandResponse, *model.AppError) {
+	tr := p.userTranslator(args.UserId)
+	count := defaultHistoryCount
+	if len(params) > 0 {
+		n, err := strconv.Atoi(params[0])
+		if err != nil || n < 1 {
+			return ephemeralResponse(tr("history.usage")), nil
+		}
+		count = n
+	}
+	if count > maxHistoryCount {
+		count = maxHistoryCount
+	}
+
+	matches, appErr := p.getRecentMatches(args.ChannelId, count, time.Time{})
+	if appErr != nil {
+		return nil, appErr
+	}
+	if len(matches) == 0 {
+		return ephemeralResponse(tr("history.empty")), nil
+	}
+
+	location := p.getConfiguration().location
+	cache := map[string]string{}
+	text := tr("history.title") + "\n"
+	for _, match := range matches {
+		text += fmt.Sprintf("- %s: %s\n", match.StartTime.In(location).Format(tr("time.format.datetime")), p.formatMatch(match, cache))
+	}
+
+	return ephemeralResponse(text), nil
+}
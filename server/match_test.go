@@ -0,0 +1,118 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+func TestParseScore(t *testing.T) {
+	errorTables := [][]string{
+		{},
+		{"10"},
+		{"10", "7", "3"},
+		{"zehn", "7"},
+		{"10", "-1"},
+		{"5", "5"},
+	}
+
+	for _, args := range errorTables {
+		if _, err := parseScore(args); err == nil {
+			t.Errorf("parseScore should fail for args: %v", args)
+		}
+	}
+
+	score, err := parseScore([]string{"7", "10"})
+	if err != nil || score != [2]int{7, 10} {
+		t.Errorf("parseScore returns unexpected results, got: %v (%v), want: %v", score, err, [2]int{7, 10})
+	}
+}
+
+func TestNewMatch(t *testing.T) {
+	teams := splitTeams([]Player{*horst, *baerbel, *etienne, *ingebork})
+	match := NewMatch("channel-a", teams, time.Now())
+
+	if len(match.Teams[0]) != 2 || match.Teams[0][0] != "1" || match.Teams[1][1] != "4" {
+		t.Errorf("Teams of match were incorrect, got: %v", match.Teams)
+	}
+
+	if match.Format != "2v2" {
+		t.Errorf("Format of match was incorrect, got: %s", match.Format)
+	}
+
+	if !match.HasPlayer("3") || match.HasPlayer("5") {
+		t.Errorf("HasPlayer returns unexpected results for players %v", match.Players())
+	}
+
+	if match.Winner() != -1 {
+		t.Errorf("Match without result should not have a winner, got: %d", match.Winner())
+	}
+
+	match.Score = [2]int{6, 10}
+	match.Reported = true
+	if match.Winner() != 1 {
+		t.Errorf("Winner was incorrect, got: %d, want: %d", match.Winner(), 1)
+	}
+}
+
+func TestConcurrentResults(t *testing.T) {
+	api := newFakeAPI()
+	p := newTestPlugin(api, loadTestTranslations(t))
+
+	older := NewMatch("channel", [2][]Player{{*horst}, {*baerbel}}, time.Now().Add(-time.Hour))
+	latest := NewMatch("channel", [2][]Player{{*horst}, {*baerbel}}, time.Now())
+	for _, match := range []*Match{older, latest} {
+		if appErr := p.addMatch(match); appErr != nil {
+			t.Fatalf("Failed to store match: %s", appErr.Error())
+		}
+	}
+
+	// both players report at the same time
+	var wg sync.WaitGroup
+	texts := make([]string, 2)
+	for i, userID := range []string{"1", "2"} {
+		wg.Add(1)
+		go func(i int, userID string) {
+			defer wg.Done()
+			response, appErr := p.executeResultCommand(&model.CommandArgs{UserId: userID, ChannelId: "channel"}, []string{"10", "7"})
+			if appErr != nil {
+				t.Errorf("Report failed: %s", appErr.Error())
+				return
+			}
+			texts[i] = response.Text
+		}(i, userID)
+	}
+	wg.Wait()
+
+	if texts[0] != "" && texts[1] != "" || !strings.Contains(texts[0]+texts[1], "already reported") {
+		t.Errorf("Exactly one report should be accepted, got: %q", texts)
+	}
+
+	rating, _ := p.getRating("1")
+	if rating.Games != 1 {
+		t.Errorf("Match should be rated once, got %d games", rating.Games)
+	}
+	if match, _ := p.getMatch(older.ID); match.Reported {
+		t.Errorf("The second report should not land on the older match")
+	}
+}
+
+func TestGetRecentMatchesSince(t *testing.T) {
+	p := newTestPlugin(newFakeAPI(), loadTestTranslations(t))
+	now := time.Now()
+	for _, age := range []time.Duration{30 * 24 * time.Hour, 2 * time.Hour, time.Hour} {
+		if appErr := p.addMatch(NewMatch("channel", [2][]Player{{*horst}, {*baerbel}}, now.Add(-age))); appErr != nil {
+			t.Fatalf("Failed to store match: %s", appErr.Error())
+		}
+	}
+
+	if matches, _ := p.getRecentMatches("channel", 10, now.Add(-7*24*time.Hour)); len(matches) != 2 {
+		t.Errorf("Only the matches since the given time should be read, got: %d", len(matches))
+	}
+	if matches, _ := p.getRecentMatches("channel", 10, time.Time{}); len(matches) != 3 {
+		t.Errorf("All matches should be read without limit, got: %d", len(matches))
+	}
+}
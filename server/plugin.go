@@ -3,7 +3,6 @@ package main
 import (
 	"fmt"
 	"io/ioutil"
-	"math/rand"
 	"net/http"
 	"path/filepath"
 	"strings"
@@ -11,22 +10,16 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
-	"github.com/mattermost/mattermost-server/model"
-	"github.com/mattermost/mattermost-server/plugin"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
 )
 
 // WantLevel defines how urgent a Player wants to play
 type WantLevel int
 
 const (
-	trigger        = "kicker"
-	botUserName    = "kicker"
-	botDisplayName = "kicker BOT"
-	playerCount    = 4
 	paramMaxHour   = 24
 	paramMaxMinute = 60
-	// warnDuration is used by a timer to notify, if there are not enough players
-	warnDuration = time.Minute * time.Duration(15) // 15 Minutes
 	// WLDecline means that this Player does not want to play
 	WLDecline WantLevel = -1
 	// WLVolunteer means that this Player wants to play only if there are not enough players
@@ -39,6 +32,8 @@ const (
 type Player struct {
 	user      *model.User
 	wantLevel WantLevel
+	// automatic is set, if the answer was given by the standing answers of the user, see Prefs
+	automatic bool
 }
 
 // KickerPlugin implements the interface expected by the Mattermost server to communicate between the server and plugin processes.
@@ -54,19 +49,32 @@ type KickerPlugin struct {
 	// setConfiguration for usage.
 	configuration *configuration
 
-	enabled      bool
-	busy         bool
-	pollPost     *model.Post
-	cancelPost   *model.Post
-	endTime      time.Time
-	timer        *time.Timer
-	timerWarning *time.Timer
-	userID       string // user-ID of user who started a game
-	channelID    string
-	rootID       string
-
-	participants []Player
-	siteURL      string
+	enabled bool
+
+	// gamesLock synchronizes access to the games map.
+	gamesLock sync.Mutex
+
+	// games holds the running games, keyed by channel-ID
+	games map[string]*Game
+
+	// matchesLock synchronizes updates of the matches, match lists and lineups in the KV store.
+	// It is taken before ratingsLock.
+	matchesLock sync.Mutex
+
+	// ratingsLock synchronizes updates of the ratings in the KV store.
+	ratingsLock sync.Mutex
+	// schedulesLock synchronizes changes of the schedules and their index in the KV store.
+	schedulesLock sync.Mutex
+	// prefsLock synchronizes changes of the standing answers and the users with standing answers per channel in the KV store.
+	prefsLock sync.Mutex
+
+	// schedulerStop is closed to stop the scheduler
+	schedulerStop chan struct{}
+
+	// translations holds the messages in all languages, loaded in OnActivate
+	translations translationBundle
+
+	siteURL string
 }
 
 // ServeHTTP delegates routing to the mux Router, which is configured in OnActivate
@@ -76,17 +84,34 @@ func (p *KickerPlugin) ServeHTTP(c *plugin.Context, w http.ResponseWriter, r *ht
 
 // See https://developers.mattermost.com/extend/plugins/server/reference/
 
-// OnActivate registers a command and a bot, sets up routing, and initializes the plugin
-func (p *KickerPlugin) OnActivate() error {
-	err := p.API.RegisterCommand(&model.Command{
-		Trigger:          trigger,
-		Description:      "TODO: describe me",
-		DisplayName:      botDisplayName,
+// registerCommand registers the slash command with the configured trigger
+func (p *KickerPlugin) registerCommand() error {
+	configuration := p.getConfiguration()
+	tr := p.translations.translator(p.defaultLocale())
+
+	return p.API.RegisterCommand(&model.Command{
+		Trigger:          configuration.Trigger,
+		Description:      tr("command.description"),
+		DisplayName:      configuration.BotDisplayName,
 		AutoComplete:     true,
-		AutoCompleteDesc: "Startet den " + botDisplayName + ", e.g. /" + trigger + " 12 30",
-		AutoCompleteHint: "[hour] [minute]",
+		AutoCompleteDesc: tr("command.autocomplete", configuration.BotDisplayName, configuration.Trigger),
+		AutoCompleteHint: p.autocompleteHint(),
+		AutocompleteData: p.autocompleteData(tr),
 	})
+}
+
+// OnActivate registers a command and a bot, sets up routing, and initializes the plugin
+func (p *KickerPlugin) OnActivate() error {
+	bundlePath, err := p.API.GetBundlePath()
 	if err != nil {
+		return appError("failed to get bundle path", err)
+	}
+
+	if p.translations, err = loadTranslationBundle(filepath.Join(bundlePath, "assets", "i18n")); err != nil {
+		return appError("failed to load translations", err)
+	}
+
+	if err = p.registerCommand(); err != nil {
 		return err
 	}
 
@@ -95,9 +120,10 @@ func (p *KickerPlugin) OnActivate() error {
 	p.siteURL = *config.ServiceSettings.SiteURL
 
 	// Init bot
+	configuration := p.getConfiguration()
 	bot := &model.Bot{
-		Username:    botUserName,
-		DisplayName: botDisplayName,
+		Username:    configuration.BotUserName,
+		DisplayName: configuration.BotDisplayName,
 	}
 
 	botUserID, appErr := p.Helpers.EnsureBot(bot)
@@ -117,128 +143,193 @@ func (p *KickerPlugin) OnActivate() error {
 	p.router.HandleFunc("/volunteer", p.VolunteerHandler)
 	p.router.HandleFunc("/decline", p.DeclineHandler)
 	p.router.HandleFunc("/cancel-game", p.CancelGameHandler)
+	p.router.HandleFunc("/reschedule", p.RescheduleHandler)
+	p.router.HandleFunc("/drop-out", p.DropOutHandler)
 
 	// serve static assets
-	bundlePath, err := p.API.GetBundlePath()
-	if err != nil {
-		return appError("failed to get bundle path", err)
-	}
 	p.router.PathPrefix("/assets/").Handler(http.StripPrefix("/assets/", http.FileServer(http.Dir(filepath.Join(bundlePath, "assets")))))
 
 	// initialize plugin
 	p.enabled = true
-	p.busy = false
+	p.games = make(map[string]*Game)
+
+	// continue games, which were running before the plugin was stopped
+	if err = p.restoreGames(); err != nil {
+		return err
+	}
+
+	p.startScheduler()
 
 	return nil
 }
 
-func (p *KickerPlugin) setUserWantLevel(userID string, wantLevel WantLevel) *model.AppError {
-	// get user info from Mattermost API
-	user, err := p.API.GetUser(userID)
-	if err != nil {
-		return appError("failed to get user data", err)
-	}
+// getGame returns the game running in the given channel, or nil
+func (p *KickerPlugin) getGame(channelID string) *Game {
+	p.gamesLock.Lock()
+	defer p.gamesLock.Unlock()
 
-	p.removeParticipantByID(user.Id)
-	p.participants = append(p.participants, Player{
-		user:      user,
-		wantLevel: wantLevel,
-	})
+	return p.games[channelID]
+}
 
-	p.updatePollPost()
+// addGame registers the given game for its channel. Returns false if the channel is busy.
+func (p *KickerPlugin) addGame(game *Game) bool {
+	p.gamesLock.Lock()
+	defer p.gamesLock.Unlock()
 
-	return nil
+	if _, busy := p.games[game.channelID]; busy {
+		return false
+	}
+	p.games[game.channelID] = game
+	return true
 }
 
-func (p *KickerPlugin) handleParticipationRequest(w http.ResponseWriter, r *http.Request, wantLevel WantLevel) {
-	err := p.setUserWantLevel(r.Header.Get("Mattermost-User-Id"), wantLevel)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(w, "{\"response\":\"Invalid User\"}\n")
-		return
-	}
+// removeGame unregisters the given game, if it is still the one running in its channel.
+// Returns false if the game was already removed (e.g. canceled or ended).
+func (p *KickerPlugin) removeGame(game *Game) bool {
+	p.gamesLock.Lock()
+	defer p.gamesLock.Unlock()
 
-	w.WriteHeader(http.StatusOK)
-	fmt.Fprintf(w, "{\"response\":\"OK\"}\n")
+	if p.games[game.channelID] != game {
+		return false
+	}
+	delete(p.games, game.channelID)
+	return true
 }
 
-// ParticipateHandler handles participation requests
-func (p *KickerPlugin) ParticipateHandler(w http.ResponseWriter, r *http.Request) {
-	p.handleParticipationRequest(w, r, WLParticipate)
+// setUserWantLevel records the answer of the given user and updates the poll post while the game is
+// still locked, so that no older state can overwrite it. Returns errGameEnded if the poll is already over.
+func (p *KickerPlugin) setUserWantLevel(game *Game, userID string, wantLevel WantLevel) error {
+	// get user info from Mattermost API
+	user, appErr := p.API.GetUser(userID)
+	if appErr != nil {
+		return appError("failed to get user data", appErr)
+	}
+
+	game.lock.Lock()
+	defer game.lock.Unlock()
+	if game.ended {
+		return errGameEnded
+	}
+
+	game.setPlayer(user, wantLevel)
+	p.updatePollPost(game)
+	p.saveGame(game)
+	return nil
 }
 
-// VolunteerHandler handles volunteering requests
-func (p *KickerPlugin) VolunteerHandler(w http.ResponseWriter, r *http.Request) {
-	p.handleParticipationRequest(w, r, WLVolunteer)
+// canManageGame checks if the given user may reschedule or cancel the given game, see canManage
+func (p *KickerPlugin) canManageGame(game *Game, userID string) bool {
+	return p.canManage(userID, game.userID, game.channelID)
 }
 
-// DeclineHandler handles declining request
-func (p *KickerPlugin) DeclineHandler(w http.ResponseWriter, r *http.Request) {
-	p.handleParticipationRequest(w, r, WLDecline)
+// canManage checks if the given user may change something created by creatorID in the given channel:
+// the creator, system admins and admins of the channel
+func (p *KickerPlugin) canManage(userID, creatorID, channelID string) bool {
+	return userID == creatorID ||
+		p.API.HasPermissionTo(userID, model.PERMISSION_MANAGE_SYSTEM) ||
+		p.API.HasPermissionToChannel(userID, channelID, model.PERMISSION_MANAGE_CHANNEL_ROLES)
 }
 
-// CancelGameHandler handles canceling game requests
-func (p *KickerPlugin) CancelGameHandler(w http.ResponseWriter, r *http.Request) {
-	user, err := p.API.GetUser(r.Header.Get("Mattermost-User-Id"))
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(w, "{\"response\":\"Invalid User\"}\n")
-		return
+// rescheduleGame moves the end of the poll of the given game, i.e. the start of the game, to the time
+// returned by move for the current end, re-arms its timers and announces the new time in the thread.
+// Returns the new end, or errGameEnded if the poll is already over.
+func (p *KickerPlugin) rescheduleGame(game *Game, move func(endTime time.Time) time.Time, userID string) (time.Time, error) {
+	user, appErr := p.API.GetUser(userID)
+	if appErr != nil {
+		return time.Time{}, appError("failed to get user data", appErr)
 	}
 
-	if user.Id != p.userID {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(w, "{\"response\":\"Not Authorized\"}\n")
-		return
+	game.lock.Lock()
+	defer game.lock.Unlock()
+	if game.ended {
+		return time.Time{}, errGameEnded
 	}
 
-	if p.busy {
-		p.timer.Stop()
-		p.timerWarning.Stop()
-		p.busy = false
+	game.stopTimers()
+	game.endTime = move(game.endTime).UTC()
+	game.warned = false
+	p.startTimers(game)
+	p.updatePollPost(game)
+	p.saveGame(game)
 
-		p.API.CreatePost(&model.Post{
-			UserId:    p.botUserID,
-			ChannelId: p.channelID,
-			Message:   "Bot wurde gestoppt!",
-			RootId:    p.rootID,
-			Type:      model.POST_DEFAULT,
-		})
-
-		p.removePollPost()
-		p.removeCancelPost()
+	// answer in the thread of the poll, or the thread the poll was started in
+	rootID := game.rootID
+	if rootID == "" {
+		rootID = game.pollPost.Id
 	}
+	tr := p.translations.translator(game.locale)
+	p.API.CreatePost(&model.Post{
+		UserId:    p.botUserID,
+		ChannelId: game.channelID,
+		Message:   tr("game.rescheduled", user.Username, formatStartTime(game.endTime, p.getConfiguration().location, time.Now(), tr)),
+		RootId:    rootID,
+		Type:      model.POST_DEFAULT,
+	})
 
-	w.WriteHeader(http.StatusOK)
-	fmt.Fprintf(w, "{\"response\":\"OK\"}\n")
+	return game.endTime, nil
 }
 
-func (p *KickerPlugin) removeParticipantByID(id string) {
-	var participants []Player
-	for _, participant := range p.participants {
-		if id != participant.user.Id {
-			participants = append(participants, participant)
-		}
+// cancelGame stops the given game on behalf of the given user, and removes its posts
+func (p *KickerPlugin) cancelGame(game *Game, userID string) {
+	name := userID
+	if user, appErr := p.API.GetUser(userID); appErr == nil {
+		name = user.Username
+	}
+
+	game.lock.Lock()
+	defer game.lock.Unlock()
+	if !game.end() {
+		return
 	}
-	p.participants = participants
+	p.removeGame(game)
+	p.deleteStoredGame(game)
+
+	p.API.CreatePost(&model.Post{
+		UserId:    p.botUserID,
+		ChannelId: game.channelID,
+		Message:   p.translations.translate(game.locale, "game.canceled", name),
+		RootId:    game.rootID,
+		Type:      model.POST_DEFAULT,
+	})
+
+	p.removePollPost(game)
+	p.removeCancelPost(game)
 }
 
-func (p *KickerPlugin) updatePollPost() {
-	model.ParseSlackAttachment(p.pollPost, p.buildSlackAttachments())
-	p.pollPost, _ = p.API.UpdatePost(p.pollPost)
+// updatePollPost shows the current state of the given game in its poll. The game must be locked.
+func (p *KickerPlugin) updatePollPost(game *Game) {
+	model.ParseSlackAttachment(game.pollPost, p.buildSlackAttachments(game))
+	game.pollPost, _ = p.API.UpdatePost(game.pollPost)
 }
 
-func (p *KickerPlugin) removePollPost() {
-	p.API.DeletePost(p.pollPost.Id)
+func (p *KickerPlugin) removePollPost(game *Game) {
+	p.API.DeletePost(game.pollPost.Id)
 }
 
-func (p *KickerPlugin) removeCancelPost() {
-	p.API.DeleteEphemeralPost(p.userID, p.cancelPost.Id)
+func (p *KickerPlugin) removeCancelPost(game *Game) {
+	p.API.DeleteEphemeralPost(game.userID, game.cancelPost.Id)
 }
 
-// OnDeactivate unregisters the command
+// OnDeactivate stops the timers of all running games. The games stay in the KV store
+// and are continued by OnActivate.
 func (p *KickerPlugin) OnDeactivate() error {
 	p.enabled = false
+	p.stopScheduler()
+
+	// the games are locked one by one, as the timers lock the game before the list of games
+	p.gamesLock.Lock()
+	games := []*Game{}
+	for _, game := range p.games {
+		games = append(games, game)
+	}
+	p.gamesLock.Unlock()
+
+	for _, game := range games {
+		game.lock.Lock()
+		game.stopTimers()
+		game.lock.Unlock()
+	}
+
 	return nil
 }
 
@@ -267,81 +358,83 @@ func (p *KickerPlugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs
 	if p.API == nil {
 		return nil, appError("Cannot access the plugin API.", nil)
 	}
-	if strings.HasPrefix(args.Command, "/"+trigger) {
+	if strings.HasPrefix(args.Command, "/"+p.getConfiguration().Trigger) {
 		return p.executeCommand(args)
 	}
 
 	return nil, appError("Command trigger "+args.Command+"is not supported by this plugin.", nil)
 }
 
-// executeCommand checks the given arguments and the internal state, and returns the according message
-func (p *KickerPlugin) executeCommand(args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
-	sassyResponseText := fmt.Sprintf("![](%s/plugins/%s/assets/sassy.webp)", p.siteURL, manifest.ID)
+// executeStartCommand starts a new game in the channel, at the time given by params
+func (p *KickerPlugin) executeStartCommand(args *model.CommandArgs, params []string) (*model.CommandResponse, *model.AppError) {
 	busyResponsetext := fmt.Sprintf("![](%s/plugins/%s/assets/busy.webp)", p.siteURL, manifest.ID)
 
-	// check if kicker is busy
-	if p.busy {
-		return &model.CommandResponse{ResponseType: model.COMMAND_RESPONSE_TYPE_EPHEMERAL, Text: busyResponsetext}, nil
+	// parse the start time, which is the end of the poll, in the timezone of the user
+	configuration := p.getConfiguration()
+	user, appErr := p.API.GetUser(args.UserId)
+	if appErr != nil {
+		return nil, appErr
 	}
+	tr := p.translations.translator(user.Locale)
 
-	// flag busy
-	p.busy = true
-
-	// clear participants
-	p.participants = []Player{}
-
-	// set user, channel and root ID
-	p.userID = args.UserId
-	p.channelID = args.ChannelId
-	p.rootID = args.RootId
+	teamSize := configuration.defaultTeamSize
+	format, formatGiven, params := extractFormat(params)
+	if formatGiven {
+		var ok bool
+		if teamSize, ok = parseFormat(format); !ok {
+			return ephemeralResponse(tr("format.invalid", format, maxTeamSize)), nil
+		}
+	}
 
-	// parse Args
-	parsedArgs, parseError := ParseArgs(args.Command)
+	loc := userLocation(user, configuration.location)
+	endTime, parseError := ParseTime(params, time.Now().In(loc), configuration.defaultHour)
 	if parseError != nil {
-		p.busy = false
-		return &model.CommandResponse{ResponseType: model.COMMAND_RESPONSE_TYPE_EPHEMERAL, Text: sassyResponseText}, nil
+		return ephemeralResponse(parseError.translate(tr) + " " + tr("time.error.examples")), nil
 	}
-	// get the wait-duration until poll ends
-	loc, _ := time.LoadLocation("Europe/Berlin")
-	p.endTime = getEndTime(parsedArgs...)
-	duration := p.endTime.Sub(time.Now().In(loc))
-	warnDur := duration - warnDuration
 
-	// if invalid, return sassy response
-	if duration <= 0 {
-		p.busy = false
-		return &model.CommandResponse{ResponseType: model.COMMAND_RESPONSE_TYPE_EPHEMERAL, Text: sassyResponseText}, nil
-	}
+	// the standing answers are read before the game is locked, as that takes a request per user
+	automaticPlayers := p.resolvePrefs(args.ChannelId, endTime)
 
-	// Set timerWarning if we have at least 15 minutes before starting
-	if warnDur > 0 {
-		p.timerWarning = time.AfterFunc(warnDur, p.CheckEnoughPlayer)
-	}
+	// check if kicker is busy in this channel, and flag it busy otherwise
+	game := NewGame(args.UserId, args.ChannelId, args.RootId)
+	game.endTime = endTime.UTC()
+	game.locale = p.channelLocale(args.ChannelId)
+	game.teamSize = teamSize
 
-	// delay execution until endTime is reached
-	p.timer = time.AfterFunc(duration, p.CreateEndPollPost)
+	// clicks and commands wait until the posts and timers of the new game are set up
+	game.lock.Lock()
+	defer game.lock.Unlock()
+	if !p.addGame(game) {
+		return &model.CommandResponse{ResponseType: model.COMMAND_RESPONSE_TYPE_EPHEMERAL, Text: busyResponsetext}, nil
+	}
+	for _, player := range automaticPlayers {
+		game.setAutomaticPlayer(player.user, player.wantLevel)
+	}
 
 	// create bot-post for initiating the poll
 	post := &model.Post{
 		UserId:    p.botUserID,
-		ChannelId: p.channelID,
+		ChannelId: game.channelID,
 		Message:   "",
-		RootId:    p.rootID,
+		RootId:    game.rootID,
 		Type:      model.POST_DEFAULT,
 	}
-	model.ParseSlackAttachment(post, p.buildSlackAttachments())
-	p.pollPost, _ = p.API.CreatePost(post)
+	model.ParseSlackAttachment(post, p.buildSlackAttachments(game))
+	game.pollPost, _ = p.API.CreatePost(post)
 
 	// create bot-post for canceling the poll (only visible to poll creator)
 	cancelPost := &model.Post{
 		UserId:    p.botUserID,
-		ChannelId: p.channelID,
+		ChannelId: game.channelID,
 		Message:   "",
-		RootId:    p.rootID,
+		RootId:    game.rootID,
 		Type:      model.POST_DEFAULT,
 	}
-	model.ParseSlackAttachment(cancelPost, p.buildCancelGameAttachment())
-	p.cancelPost = p.API.SendEphemeralPost(p.userID, cancelPost)
+	model.ParseSlackAttachment(cancelPost, p.buildCancelGameAttachment(game, tr))
+	game.cancelPost = p.API.SendEphemeralPost(game.userID, cancelPost)
+
+	p.startTimers(game)
+	p.saveGame(game)
 
 	return &model.CommandResponse{
 		ResponseType: model.COMMAND_RESPONSE_TYPE_EPHEMERAL,
@@ -349,101 +442,97 @@ func (p *KickerPlugin) executeCommand(args *model.CommandArgs) (*model.CommandRe
 	}, nil
 }
 
-// ChoosePlayers returns 4 random Player (if possible).
-// Participants are prefered over Volunteers.
-func (p *KickerPlugin) ChoosePlayers() []Player {
-	var returnPlayer []Player
-	participants := p.GetParticipants()
-	volunteers := p.GetVolunteers()
+// startTimers arms the warning, reminder and end timer of the given game, depending on its endTime.
+// The game must be locked.
+func (p *KickerPlugin) startTimers(game *Game) {
+	configuration := p.getConfiguration()
+	duration := time.Until(game.endTime)
+	warnDur := duration - configuration.warnDuration
 
-	if len(participants)+len(volunteers) < playerCount {
-		// not enough players! return all that wanted to play
-		return append(participants, volunteers...)
+	// Set timerWarning if the warning is enabled and we have enough time before starting
+	if configuration.warnDuration > 0 && warnDur > 0 {
+		game.timerWarning = time.AfterFunc(warnDur, func() { p.CheckEnoughPlayer(game) })
 	}
 
-	// generate seed depending on server-time
-	rand.Seed(time.Now().UnixNano())
+	if remindDur := duration - configuration.remindDuration; configuration.remindDuration > 0 && remindDur > 0 {
+		game.timerRemind = time.AfterFunc(remindDur, func() { p.sendReminders(game) })
+	}
 
-	if len(participants) >= playerCount {
-		// enough participants
-		for i := 0; i < playerCount; i++ {
-			// add random participants
-			randIndex := rand.Intn(len(participants))
-			returnPlayer = append(returnPlayer, participants[randIndex])
-			participants = remove(participants, randIndex)
-		}
-	} else {
-		// not enough participants
-		// take all participants
-		returnPlayer = append(returnPlayer, participants...)
-		// add random volunteers
-		restPlayerCount := playerCount - len(returnPlayer)
-		for i := 0; i < restPlayerCount; i++ {
-			randIndex := rand.Intn(len(volunteers))
-			returnPlayer = append(returnPlayer, volunteers[randIndex])
-			volunteers = remove(volunteers, randIndex)
-		}
+	// delay execution until endTime is reached
+	game.timer = time.AfterFunc(duration, func() { p.CreateEndPollPost(game) })
+}
 
+// actionContext returns the context passed along with every button of the given game
+func actionContext(game *Game) map[string]interface{} {
+	return map[string]interface{}{
+		"channel_id": game.channelID,
+		"game_id":    game.id,
 	}
-	return returnPlayer
 }
 
-func (p *KickerPlugin) buildSlackAttachments() []*model.SlackAttachment {
+func (p *KickerPlugin) buildSlackAttachments(game *Game) []*model.SlackAttachment {
+	tr := p.translations.translator(game.locale)
 	actions := []*model.PostAction{}
 
 	actions = append(actions, &model.PostAction{
-		Name: "Bin dabei 👍",
+		Name: tr("poll.participate"),
 		Type: model.POST_ACTION_TYPE_BUTTON,
 		Integration: &model.PostActionIntegration{
-			URL: fmt.Sprintf("%s/plugins/%s/participate", p.siteURL, manifest.ID),
+			URL:     fmt.Sprintf("%s/plugins/%s/participate", p.siteURL, manifest.ID),
+			Context: actionContext(game),
 		},
 	})
 
 	actions = append(actions, &model.PostAction{
-		Name: "Wenn sich sonst keiner traut 👉",
+		Name: tr("poll.volunteer"),
 		Type: model.POST_ACTION_TYPE_BUTTON,
 		Integration: &model.PostActionIntegration{
-			URL: fmt.Sprintf("%s/plugins/%s/volunteer", p.siteURL, manifest.ID),
+			URL:     fmt.Sprintf("%s/plugins/%s/volunteer", p.siteURL, manifest.ID),
+			Context: actionContext(game),
 		},
 	})
 
 	actions = append(actions, &model.PostAction{
-		Name: "Och nö 👎",
+		Name: tr("poll.decline"),
 		Type: model.POST_ACTION_TYPE_BUTTON,
 		Integration: &model.PostActionIntegration{
-			URL: fmt.Sprintf("%s/plugins/%s/decline", p.siteURL, manifest.ID),
+			URL:     fmt.Sprintf("%s/plugins/%s/decline", p.siteURL, manifest.ID),
+			Context: actionContext(game),
 		},
 	})
 
+	botDisplayName := p.getConfiguration().BotDisplayName
+
 	return []*model.SlackAttachment{{
 		AuthorName: botDisplayName,
-		Title:      "Der " + botDisplayName + " hat euch herausgefordert! Wer möchte teilnehmen?",
-		Text:       fmt.Sprintf("Kickern startet um %02d:%02d Uhr.", p.endTime.Hour(), p.endTime.Minute()),
+		Title:      tr("poll.title", botDisplayName),
+		Text:       tr("poll.text", formatName(game.teamSize), formatStartClock(game.endTime, p.getConfiguration().location, time.Now(), tr)),
 		Actions:    actions,
-	}, p.buildParticipantsAttachment()}
+	}, p.buildParticipantsAttachment(game)}
 }
 
-func (p *KickerPlugin) buildParticipantsAttachment() *model.SlackAttachment {
-	participants := p.GetParticipants()
-	volunteers := p.GetVolunteers()
-	decliners := p.GetDecliners()
+func (p *KickerPlugin) buildParticipantsAttachment(game *Game) *model.SlackAttachment {
+	participants := game.GetParticipants()
+	volunteers := game.GetVolunteers()
+	decliners := game.GetDecliners()
 
 	if len(participants) == 0 && len(volunteers) == 0 && len(decliners) == 0 {
 		return nil
 	}
 
+	tr := p.translations.translator(game.locale)
 	text := ""
 
 	if len(participants) > 0 {
-		text += "👍: " + JoinPlayerNames(participants) + "\n"
+		text += "👍: " + joinAnswerNames(participants, tr) + "\n"
 	}
 
 	if len(volunteers) > 0 {
-		text += "👉: " + JoinPlayerNames(volunteers) + "\n"
+		text += "👉: " + joinAnswerNames(volunteers, tr) + "\n"
 	}
 
 	if len(decliners) > 0 {
-		text += "👎: " + JoinPlayerNames(decliners) + "\n"
+		text += "👎: " + joinAnswerNames(decliners, tr) + "\n"
 	}
 
 	return &model.SlackAttachment{
@@ -451,95 +540,131 @@ func (p *KickerPlugin) buildParticipantsAttachment() *model.SlackAttachment {
 	}
 }
 
-func (p *KickerPlugin) buildCancelGameAttachment() []*model.SlackAttachment {
+func (p *KickerPlugin) buildCancelGameAttachment(game *Game, tr translateFunc) []*model.SlackAttachment {
+	return []*model.SlackAttachment{{
+		AuthorName: p.getConfiguration().BotDisplayName,
+		Title:      tr("cancel.title"),
+		Text:       tr("cancel.text"),
+		Actions:    p.buildGameControls(game, tr),
+	}}
+}
+
+// buildGameControls returns the buttons to postpone, start or stop the given game
+func (p *KickerPlugin) buildGameControls(game *Game, tr translateFunc) []*model.PostAction {
 	actions := []*model.PostAction{}
 
+	for _, minutes := range rescheduleMinutes {
+		context := actionContext(game)
+		context["minutes"] = minutes
+		actions = append(actions, &model.PostAction{
+			Name: tr("reschedule.later", minutes),
+			Type: model.POST_ACTION_TYPE_BUTTON,
+			Integration: &model.PostActionIntegration{
+				URL:     fmt.Sprintf("%s/plugins/%s/reschedule", p.siteURL, manifest.ID),
+				Context: context,
+			},
+		})
+	}
+
 	actions = append(actions, &model.PostAction{
-		Name: "Stop Bot",
+		Name: tr("reschedule.now"),
 		Type: model.POST_ACTION_TYPE_BUTTON,
 		Integration: &model.PostActionIntegration{
-			URL: fmt.Sprintf("%s/plugins/%s/cancel-game", p.siteURL, manifest.ID),
+			URL:     fmt.Sprintf("%s/plugins/%s/reschedule", p.siteURL, manifest.ID),
+			Context: actionContext(game),
 		},
 	})
 
-	return []*model.SlackAttachment{{
-		AuthorName: botDisplayName,
-		Title:      "Der Kicker wurde gestartet.",
-		Text:       "Zum Stoppen kannst du diesen Button benutzen:",
-		Actions:    actions,
-	}}
-}
-
-// GetParticipants returns all Players with the "participant" want level
-func (p *KickerPlugin) GetParticipants() []Player {
-	return p.filterParticipantsByWantlevel(WLParticipate)
-}
-
-// GetVolunteers returns all Players with the "volunteer" want level
-func (p *KickerPlugin) GetVolunteers() []Player {
-	return p.filterParticipantsByWantlevel(WLVolunteer)
-}
+	actions = append(actions, &model.PostAction{
+		Name: tr("cancel.button"),
+		Type: model.POST_ACTION_TYPE_BUTTON,
+		Integration: &model.PostActionIntegration{
+			URL:     fmt.Sprintf("%s/plugins/%s/cancel-game", p.siteURL, manifest.ID),
+			Context: actionContext(game),
+		},
+	})
 
-// GetDecliners returns all Players with the "decline" want level
-func (p *KickerPlugin) GetDecliners() []Player {
-	return p.filterParticipantsByWantlevel(WLDecline)
+	return actions
 }
 
-func (p *KickerPlugin) filterParticipantsByWantlevel(wantLevel WantLevel) []Player {
-	var players []Player
-
-	for _, player := range p.participants {
-		if player.wantLevel == wantLevel {
-			players = append(players, player)
-		}
+// CreateEndPollPost creates a post with the result of selected players of the given game.
+// The game stays locked until the result is posted, so clicks arriving meanwhile are rejected.
+func (p *KickerPlugin) CreateEndPollPost(game *Game) {
+	game.lock.Lock()
+	defer game.lock.Unlock()
+	if !game.end() {
+		// game was canceled or resolved in the meantime
+		return
 	}
+	p.removeGame(game)
+	p.deleteStoredGame(game)
 
-	return players
-}
+	p.removePollPost(game)
+	p.removeCancelPost(game)
 
-// CreateEndPollPost creates a post with the result of selected players
-func (p *KickerPlugin) CreateEndPollPost() {
-	p.removePollPost()
-	p.removeCancelPost()
-
-	chosenPlayer := p.ChoosePlayers()
+	tr := p.translations.translator(game.locale)
+	chosenPlayer, waitlist := game.ChooseLineup(p.getConfiguration().tables, p.selectionWeights(game))
 	// not enough player
-	if len(chosenPlayer) < playerCount {
+	if len(chosenPlayer) == 0 {
 		p.API.CreatePost(&model.Post{
 			UserId:    p.botUserID,
-			ChannelId: p.channelID,
-			Message:   "Quantität der Wettkämpfer insuffizient!",
-			RootId:    p.rootID,
+			ChannelId: game.channelID,
+			Message:   tr("game.not_enough_players"),
+			RootId:    game.rootID,
 			Type:      model.POST_DEFAULT,
 		})
-		p.busy = false
 		return
 	}
 
-	message := "Es nehmen teil: " + JoinPlayerNames(chosenPlayer)
+	p.updateLeftOut(game.channelID, chosenPlayer, waitlist)
 
-	p.API.CreatePost(&model.Post{
-		UserId:    p.botUserID,
-		ChannelId: p.channelID,
-		Message:   message,
-		RootId:    p.rootID,
-		Type:      model.POST_DEFAULT,
-	})
+	ratings, appErr := p.getRatings(playerIDs(chosenPlayer))
+	if appErr != nil {
+		p.API.LogError("failed to get ratings", "channel_id", game.channelID, "err", appErr.Error())
+	}
+
+	matches := []*Match{}
+	for _, players := range splitMatches(chosenPlayer, game.playerCount()) {
+		match := NewMatch(game.channelID, BalanceTeams(players, ratings), game.endTime)
+		if appErr = p.addMatch(match); appErr != nil {
+			p.API.LogError("failed to store match", "channel_id", game.channelID, "err", appErr.Error())
+		}
+		matches = append(matches, match)
+	}
 
-	p.busy = false
+	p.createLineup(game, matches, waitlist)
+	p.sendStartReminders(game.channelID, matches, nil)
 }
 
-// CheckEnoughPlayer creates a warning post, if we do not have enough players.
-func (p *KickerPlugin) CheckEnoughPlayer() {
-	players := p.ChoosePlayers()
+// CheckEnoughPlayer creates a warning post, if the given game does not have enough players,
+// and recruits the regulars of the channel, see recruitRegulars.
+func (p *KickerPlugin) CheckEnoughPlayer(game *Game) {
+	// the channel of a game never changes, so the history is read before locking it
+	history := p.recruitHistory(game.channelID)
+
+	game.lock.Lock()
+	defer game.lock.Unlock()
+	if game.ended {
+		return
+	}
+
+	configuration := p.getConfiguration()
+	players := game.ChoosePlayers(game.playerCount(), nil)
+
+	if len(players) < game.playerCount() {
+		message := p.translations.translate(game.locale, "game.warning", int(configuration.warnDuration.Minutes()))
+		if recruiting := p.recruitRegulars(game, history, game.playerCount()-len(players)); recruiting != "" {
+			message += "\n" + recruiting
+		}
 
-	if len(players) < playerCount {
 		p.API.CreatePost(&model.Post{
 			UserId:    p.botUserID,
-			ChannelId: p.channelID,
-			Message:   "Kickerrektrutenanzahl desolat. 15 Minuten bis zum Meltdown.",
-			RootId:    p.rootID,
+			ChannelId: game.channelID,
+			Message:   message,
+			RootId:    game.rootID,
 			Type:      model.POST_DEFAULT,
 		})
+		game.warned = true
+		p.saveGame(game)
 	}
 }
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
+	"time"
 
-	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/v5/model"
 )
 
 var horst = &Player{
@@ -78,14 +81,14 @@ var dieder = &Player{
 	wantLevel: WLDecline,
 }
 
-func SetupTestKickerPlugin(player []Player) *KickerPlugin {
-	return &KickerPlugin{
+func SetupTestGame(player []Player) *Game {
+	return &Game{
 		participants: player,
 	}
 }
 
 func TestGetParticipants(t *testing.T) {
-	p := SetupTestKickerPlugin([]Player{*horst, *baerbel, *kay})
+	p := SetupTestGame([]Player{*horst, *baerbel, *kay})
 
 	players := p.GetParticipants()
 
@@ -103,7 +106,7 @@ func TestGetParticipants(t *testing.T) {
 }
 
 func TestGetVolunteers(t *testing.T) {
-	p := SetupTestKickerPlugin([]Player{*horst, *baerbel, *kay})
+	p := SetupTestGame([]Player{*horst, *baerbel, *kay})
 
 	players := p.GetVolunteers()
 
@@ -117,7 +120,7 @@ func TestGetVolunteers(t *testing.T) {
 }
 
 func TestGetDecliners(t *testing.T) {
-	p := SetupTestKickerPlugin([]Player{*horst, *baerbel, *dieder})
+	p := SetupTestGame([]Player{*horst, *baerbel, *dieder})
 
 	players := p.GetDecliners()
 
@@ -157,129 +160,82 @@ func TestJoinPlayerNames(t *testing.T) {
 	}
 }
 
-func sliceEqual(a, b []int) bool {
-	if len(a) != len(b) {
-		return false
-	}
-	for i, v := range a {
-		if v != b[i] {
-			return false
-		}
-	}
-	return true
-}
+func TestParseTime(t *testing.T) {
+	loc, _ := time.LoadLocation("Europe/Berlin")
+	now := time.Date(2019, 7, 10, 0, 10, 0, 0, loc)
 
-func TestParseArgs(t *testing.T) {
 	errorTables := []struct {
 		Args string
 	}{
-		{
-			Args: "",
-		},
-		{
-			Args: "/kicker pfnort",
-		},
-		{
-			Args: "/kicker pfnort troz",
-		},
-		{
-			Args: "/kicker pfnort 12",
-		},
-		{
-			Args: "/kicker 12 pfnort",
-		},
-		{
-			Args: "/kicker 12 pfnort",
-		},
-		{
-			Args: "/kicker 12 20 pfnort",
-		},
-		{
-			Args: "/kicker 1220",
-		},
-		{
-			Args: "/kicker 24",
-		},
-		{
-			Args: "/kicker 24 1",
-		},
-		{
-			Args: "/kicker 24 60",
-		},
-		{
-			Args: "/kicker 24 -1",
-		},
-		{
-			Args: "/kicker -1",
-		},
-		{
-			Args: "/kicker -1 1",
-		},
-		{
-			Args: "/kicker -1 60",
-		},
-		{
-			Args: "/kicker -1 -1",
-		},
-		{
-			Args: "/kicker 12 60",
-		},
-		{
-			Args: "/kicker 12 -1",
-		},
-		{
-			Args: "/kicker 12.5 0",
-		},
-		{
-			Args: "/kicker 1.8446744e+19 0",
-		},
+		{Args: "pfnort"},
+		{Args: "pfnort troz"},
+		{Args: "pfnort 12"},
+		{Args: "12 pfnort"},
+		{Args: "12 20 pfnort"},
+		{Args: "1220"},
+		{Args: "24"},
+		{Args: "24 1"},
+		{Args: "24 60"},
+		{Args: "24 -1"},
+		{Args: "-1"},
+		{Args: "-1 1"},
+		{Args: "-1 60"},
+		{Args: "-1 -1"},
+		{Args: "12 60"},
+		{Args: "12 -1"},
+		{Args: "12.5 0"},
+		{Args: "1.8446744e+19 0"},
+		{Args: "0"},
+		{Args: "0:05"},
+		{Args: "13pm"},
+		{Args: "0am"},
+		{Args: "in"},
+		{Args: "in 0m"},
+		{Args: "in soon"},
+		{Args: "now 12"},
+		{Args: "tomorrow pfnort"},
 	}
 
 	for _, table := range errorTables {
-		ints, err := ParseArgs(table.Args)
-		if len(ints) != 0 || err == nil {
-			t.Errorf("Error handling was incorrect for args: '%s', parsed ints should be empty, was: %d, err should not be nil, was: nil", table.Args, ints)
+		result, err := ParseTime(strings.Fields(table.Args), now, 12)
+		if err == nil {
+			t.Errorf("Error handling was incorrect for args: '%s', err should not be nil, parsed time was: %s", table.Args, result)
 		}
 	}
 
 	successTables := []struct {
 		Args   string
-		Result []int
+		Result time.Time
 	}{
-		{
-			Args:   "/kicker",
-			Result: []int{12},
-		},
-		{
-			Args:   "/kicker 12",
-			Result: []int{12},
-		},
-		{
-			Args:   "/kicker 0",
-			Result: []int{0},
-		},
-		{
-			Args:   "/kicker 23",
-			Result: []int{23},
-		},
-		{
-			Args:   "/kicker 12 30",
-			Result: []int{12, 30},
-		},
-		{
-			Args:   "/kicker 12 0",
-			Result: []int{12, 0},
-		},
-		{
-			Args:   "/kicker 12 59",
-			Result: []int{12, 59},
-		},
+		{Args: "", Result: time.Date(2019, 7, 10, 12, 0, 0, 0, loc)},
+		{Args: "12", Result: time.Date(2019, 7, 10, 12, 0, 0, 0, loc)},
+		{Args: "23", Result: time.Date(2019, 7, 10, 23, 0, 0, 0, loc)},
+		{Args: "0 30", Result: time.Date(2019, 7, 10, 0, 30, 0, 0, loc)},
+		{Args: "12 30", Result: time.Date(2019, 7, 10, 12, 30, 0, 0, loc)},
+		{Args: "12 0", Result: time.Date(2019, 7, 10, 12, 0, 0, 0, loc)},
+		{Args: "12 59", Result: time.Date(2019, 7, 10, 12, 59, 0, 0, loc)},
+		{Args: "12:30", Result: time.Date(2019, 7, 10, 12, 30, 0, 0, loc)},
+		{Args: "12.30", Result: time.Date(2019, 7, 10, 12, 30, 0, 0, loc)},
+		{Args: "12 Uhr", Result: time.Date(2019, 7, 10, 12, 0, 0, 0, loc)},
+		{Args: "1pm", Result: time.Date(2019, 7, 10, 13, 0, 0, 0, loc)},
+		{Args: "1:45 PM", Result: time.Date(2019, 7, 10, 13, 45, 0, 0, loc)},
+		{Args: "12pm", Result: time.Date(2019, 7, 10, 12, 0, 0, 0, loc)},
+		{Args: "11am", Result: time.Date(2019, 7, 10, 11, 0, 0, 0, loc)},
+		{Args: "today 9", Result: time.Date(2019, 7, 10, 9, 0, 0, 0, loc)},
+		{Args: "in 20m", Result: time.Date(2019, 7, 10, 0, 30, 0, 0, loc)},
+		{Args: "in 1h30m", Result: time.Date(2019, 7, 10, 1, 40, 0, 0, loc)},
+		{Args: "in 90 minutes", Result: time.Date(2019, 7, 10, 1, 40, 0, 0, loc)},
+		{Args: "in 2 hours", Result: time.Date(2019, 7, 10, 2, 10, 0, 0, loc)},
+		{Args: "now", Result: time.Date(2019, 7, 10, 0, 15, 0, 0, loc)},
+		{Args: "tomorrow", Result: time.Date(2019, 7, 11, 12, 0, 0, 0, loc)},
+		{Args: "tomorrow 9", Result: time.Date(2019, 7, 11, 9, 0, 0, 0, loc)},
+		{Args: "tomorrow 0", Result: time.Date(2019, 7, 11, 0, 0, 0, 0, loc)},
 	}
 
 	for _, table := range successTables {
-		ints, err := ParseArgs(table.Args)
-		if !sliceEqual(ints, table.Result) || err != nil {
-			t.Errorf("Success handling was incorrect for args: '%s', parsed ints should be: %d, was: %d, err should be nil, was: %s", table.Args, table.Result, ints, err)
+		result, err := ParseTime(strings.Fields(table.Args), now, 12)
+		if !result.Equal(table.Result) || err != nil {
+			t.Errorf("Success handling was incorrect for args: '%s', parsed time should be: %s, was: %s, err should be nil, was: %v", table.Args, table.Result, result, err)
 		}
 	}
 }
@@ -302,7 +258,7 @@ func TestChoosePlayers(t *testing.T) {
 	}
 
 	for _, table := range singleResultTables {
-		player := SetupTestKickerPlugin(table.Players).ChoosePlayers()
+		player := SetupTestGame(table.Players).ChoosePlayers(4, nil)
 		if !playerEqual(player, table.Result) {
 			t.Errorf("ChoosePlayers returns unexpected results")
 		}
@@ -331,7 +287,7 @@ func TestChoosePlayers(t *testing.T) {
 	}
 
 	for _, table := range multiResultTables {
-		player := SetupTestKickerPlugin(table.Players).ChoosePlayers()
+		player := SetupTestGame(table.Players).ChoosePlayers(4, nil)
 		oneResultOccured := false
 		for _, result := range table.Results {
 			if playerEqual(player, result) {
@@ -345,9 +301,40 @@ func TestChoosePlayers(t *testing.T) {
 	}
 }
 
+func TestChooseLineup(t *testing.T) {
+	game := SetupTestGame([]Player{*kay, *horst, *baerbel, *oke, *etienne, *dieder, *ingebork, *mable, *uwe})
+	game.teamSize = 1
+
+	chosen, waitlist := game.ChooseLineup(2, nil)
+	if !playerEqual(chosen, []Player{*horst, *baerbel, *etienne, *ingebork}) {
+		t.Errorf("ChooseLineup should prefer participants, got: %s", JoinPlayerNames(chosen))
+	}
+	if !playerSliceEqual(waitlist, []Player{*kay, *oke, *mable, *uwe}) {
+		t.Errorf("Waitlist should contain the volunteers in order, got: %s", JoinPlayerNames(waitlist))
+	}
+
+	chosen, waitlist = game.ChooseLineup(3, nil)
+	if len(chosen) != 6 || len(waitlist) != 2 || !containsPlayer(chosen, "1") || containsPlayer(waitlist, "1") {
+		t.Errorf("ChooseLineup should choose 3 matches, got: %s, waitlist: %s", JoinPlayerNames(chosen), JoinPlayerNames(waitlist))
+	}
+
+	game.teamSize = 2
+	chosen, waitlist = game.ChooseLineup(3, nil)
+	if len(chosen) != 8 || len(waitlist) != 0 {
+		t.Errorf("ChooseLineup should only form full matches, got: %s, waitlist: %s", JoinPlayerNames(chosen), JoinPlayerNames(waitlist))
+	}
+
+	game = SetupTestGame([]Player{*horst, *kay, *dieder})
+	game.teamSize = 2
+	chosen, waitlist = game.ChooseLineup(1, nil)
+	if len(chosen) != 0 || !playerSliceEqual(waitlist, []Player{*horst, *kay}) {
+		t.Errorf("Without a full match, everybody should wait, got: %s, waitlist: %s", JoinPlayerNames(chosen), JoinPlayerNames(waitlist))
+	}
+}
+
 func TestFilterParticipantsByWantLevel(t *testing.T) {
 	players := []Player{*horst, *baerbel, *kay, *oke, *mable, *uwe, *etienne, *dieder, *ingebork}
-	p := SetupTestKickerPlugin(players)
+	p := SetupTestGame(players)
 
 	resultTables := []struct {
 		Level  WantLevel
@@ -393,7 +380,7 @@ func TestRemoveParticipantByID(t *testing.T) {
 	}
 
 	for _, table := range resultTables {
-		p := SetupTestKickerPlugin(players)
+		p := SetupTestGame(players)
 		p.removeParticipantByID(table.ID)
 		if !playerEqual(p.participants, table.Result) {
 			t.Errorf("removeParticipantByID returns unexpected results for ID: '%s', should be: '%s', was: '%s'", table.ID, JoinPlayerNames(table.Result), JoinPlayerNames(p.participants))
@@ -420,3 +407,196 @@ func playerEqual(a, b []Player) bool {
 	}
 	return true
 }
+
+// playerSliceEqual checks if both lists contain the same players in the same order
+func playerSliceEqual(a, b []Player) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestGamesPerChannel(t *testing.T) {
+	p := &KickerPlugin{games: make(map[string]*Game)}
+
+	first := NewGame("1", "channel-a", "")
+	second := NewGame("2", "channel-b", "")
+	third := NewGame("3", "channel-a", "")
+
+	if !p.addGame(first) || !p.addGame(second) {
+		t.Errorf("Games in different channels should run concurrently")
+	}
+
+	if p.addGame(third) {
+		t.Errorf("A second game in the same channel should be rejected")
+	}
+
+	if p.getGame("channel-b") != second {
+		t.Errorf("getGame returns the wrong game for channel-b")
+	}
+
+	if !p.removeGame(first) || p.removeGame(first) {
+		t.Errorf("removeGame should only succeed once per game")
+	}
+
+	if !p.addGame(third) {
+		t.Errorf("A new game should be possible after the old one was removed")
+	}
+}
+
+func TestMarshalGame(t *testing.T) {
+	game := NewGame("1", "channel-a", "root")
+	game.endTime = time.Date(2019, 7, 1, 12, 30, 0, 0, time.UTC)
+	game.pollPost = &model.Post{Id: "poll"}
+	game.participants = []Player{*horst, *kay}
+	game.locale = "en"
+
+	data, err := marshalGame(game)
+	if err != nil {
+		t.Fatalf("marshalGame failed: %s", err)
+	}
+
+	var stored storedGame
+	if err = json.Unmarshal(data, &stored); err != nil {
+		t.Fatalf("stored game could not be parsed: %s", err)
+	}
+
+	if stored.PollPostID != "poll" || stored.ChannelID != "channel-a" || stored.UserID != "1" || stored.RootID != "root" {
+		t.Errorf("Stored game has unexpected IDs: %+v", stored)
+	}
+
+	if stored.Locale != "en" {
+		t.Errorf("Stored locale was incorrect, got: %s", stored.Locale)
+	}
+
+	if !stored.EndTime.Equal(game.endTime) {
+		t.Errorf("Stored end time was incorrect, got: %s, want: %s", stored.EndTime, game.endTime)
+	}
+
+	if len(stored.Participants) != 2 || stored.Participants[1].UserID != "5" || stored.Participants[1].WantLevel != WLVolunteer {
+		t.Errorf("Stored participants were incorrect, got: %+v", stored.Participants)
+	}
+}
+
+func TestConfigurationProcess(t *testing.T) {
+	errorTables := []func(c *configuration){
+		func(c *configuration) { c.Trigger = "" },
+		func(c *configuration) { c.Trigger = "kicker now" },
+		func(c *configuration) { c.BotDisplayName = " " },
+		func(c *configuration) { c.DefaultFormat = "2v1" },
+		func(c *configuration) { c.DefaultFormat = "0v0" },
+		func(c *configuration) { c.DefaultFormat = "four" },
+		func(c *configuration) { c.FairnessStrength = "-1" },
+		func(c *configuration) { c.FairnessStrength = "11" },
+		func(c *configuration) { c.FairnessStrength = "strong" },
+		func(c *configuration) { c.WarnMinutes = "-1" },
+		func(c *configuration) { c.TimeZone = "Mars/Olympus_Mons" },
+		func(c *configuration) { c.DefaultHour = "24" },
+	}
+
+	for i, modify := range errorTables {
+		c := defaultConfiguration()
+		modify(c)
+		if err := c.process(); err == nil {
+			t.Errorf("Invalid configuration %d was accepted: %+v", i, c)
+		}
+	}
+
+	c := defaultConfiguration()
+	c.DefaultFormat = "1v1"
+	c.WarnMinutes = "5"
+	c.DefaultHour = "13"
+	c.FairnessStrength = "0.5"
+	if err := c.process(); err != nil {
+		t.Fatalf("Valid configuration was rejected: %s", err)
+	}
+
+	if c.defaultTeamSize != 1 || c.warnDuration != 5*time.Minute || c.defaultHour != 13 || c.fairnessStrength != 0.5 || c.location.String() != "Europe/Berlin" {
+		t.Errorf("Computed configuration values were incorrect: %+v", c)
+	}
+}
+
+func TestUserLocation(t *testing.T) {
+	fallback, _ := time.LoadLocation("Europe/Berlin")
+
+	tables := []struct {
+		Timezone model.StringMap
+		Result   string
+	}{
+		{
+			Timezone: model.StringMap{},
+			Result:   "Europe/Berlin",
+		},
+		{
+			Timezone: model.StringMap{"useAutomaticTimezone": "true", "automaticTimezone": "America/New_York", "manualTimezone": "Asia/Tokyo"},
+			Result:   "America/New_York",
+		},
+		{
+			Timezone: model.StringMap{"useAutomaticTimezone": "false", "automaticTimezone": "America/New_York", "manualTimezone": "Asia/Tokyo"},
+			Result:   "Asia/Tokyo",
+		},
+		{
+			Timezone: model.StringMap{"useAutomaticTimezone": "false", "manualTimezone": "Mars/Olympus_Mons"},
+			Result:   "Europe/Berlin",
+		},
+	}
+
+	for _, table := range tables {
+		loc := userLocation(&model.User{Timezone: table.Timezone}, fallback)
+		if loc.String() != table.Result {
+			t.Errorf("Location was incorrect for timezone %v, got: %s, want: %s", table.Timezone, loc, table.Result)
+		}
+	}
+}
+
+func TestFormatStartTime(t *testing.T) {
+	translations := loadTestTranslations(t)
+	de := translations.translator("de")
+	loc, _ := time.LoadLocation("Europe/Berlin")
+	now := time.Date(2019, 7, 10, 9, 5, 0, 0, time.UTC)
+
+	tables := []struct {
+		Start  time.Time
+		Result string
+	}{
+		{
+			Start:  time.Date(2019, 7, 10, 9, 30, 0, 0, time.UTC),
+			Result: "11:30 Uhr (Europe/Berlin, in 25 Minuten)",
+		},
+		{
+			Start:  time.Date(2019, 7, 10, 10, 6, 0, 0, time.UTC),
+			Result: "12:06 Uhr (Europe/Berlin, in 1 Stunde 1 Minute)",
+		},
+		{
+			Start:  time.Date(2019, 7, 11, 7, 5, 0, 0, time.UTC),
+			Result: "11.07. 09:05 Uhr (Europe/Berlin, in 22 Stunden)",
+		},
+		{
+			Start:  now,
+			Result: "11:05 Uhr (Europe/Berlin, gleich)",
+		},
+	}
+
+	for _, table := range tables {
+		result := formatStartTime(table.Start, loc, now, de)
+		if result != table.Result {
+			t.Errorf("Formatted start time was incorrect, got: %s, want: %s", result, table.Result)
+		}
+	}
+
+	en := translations.translator("en")
+	result := formatStartTime(time.Date(2019, 7, 11, 11, 5, 0, 0, time.UTC), loc, now, en)
+	if expected := "Jul 11, 1:05 PM (Europe/Berlin, in 26 hours)"; result != expected {
+		t.Errorf("Formatted English start time was incorrect, got: %s, want: %s", result, expected)
+	}
+
+	result = formatStartClock(time.Date(2019, 7, 10, 9, 30, 0, 0, time.UTC), loc, now, de)
+	if expected := "11:30 Uhr (Europe/Berlin)"; result != expected {
+		t.Errorf("Formatted start clock should not contain the duration, got: %s, want: %s", result, expected)
+	}
+}
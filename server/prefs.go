@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const (
+	// prefsKeyPrefix prefixes the KV store keys of the standing answers per channel and user,
+	// followed by the channel-ID and the user-ID
+	prefsKeyPrefix = "prefs_"
+	// prefsChannelKeyPrefix prefixes the KV store keys of the users, who set standing answers in a channel,
+	// followed by the channel-ID
+	prefsChannelKeyPrefix = "prefschannel_"
+)
+
+// prefAnswers maps the answers of the prefs command to the want levels; "none" removes the standing answer
+var prefAnswers = map[string]WantLevel{
+	"participate": WLParticipate,
+	"volunteer":   WLVolunteer,
+	"decline":     WLDecline,
+}
+
+// prefAnswerOrder is the order, in which the standing answers are listed
+var prefAnswerOrder = []string{"participate", "volunteer", "decline"}
+
+// Prefs are the standing answers of a user in a channel per weekday, given automatically to its new polls
+type Prefs map[time.Weekday]WantLevel
+
+func prefsKey(channelID, userID string) string {
+	return prefsKeyPrefix + channelID + "_" + userID
+}
+
+func prefsChannelKey(channelID string) string {
+	return prefsChannelKeyPrefix + channelID
+}
+
+// getPrefs reads the standing answers of the given user in the given channel from the KV store
+func (p *KickerPlugin) getPrefs(channelID, userID string) (Prefs, *model.AppError) {
+	prefs := Prefs{}
+	data, appErr := p.API.KVGet(prefsKey(channelID, userID))
+	if appErr != nil {
+		return nil, appErr
+	}
+	if data != nil {
+		if err := json.Unmarshal(data, &prefs); err != nil {
+			return nil, appError("failed to parse prefs", err)
+		}
+	}
+	return prefs, nil
+}
+
+// savePrefs writes the standing answers of the given user in the given channel to the KV store,
+// or removes them if empty, and updates the users with standing answers in the channel. The prefs must be locked.
+func (p *KickerPlugin) savePrefs(channelID, userID string, prefs Prefs) *model.AppError {
+	if len(prefs) == 0 {
+		if appErr := p.API.KVDelete(prefsKey(channelID, userID)); appErr != nil {
+			return appErr
+		}
+		return p.updatePrefsUsers(channelID, userID, false)
+	}
+
+	data, err := json.Marshal(prefs)
+	if err != nil {
+		return appError("failed to serialize prefs", err)
+	}
+	if appErr := p.API.KVSet(prefsKey(channelID, userID), data); appErr != nil {
+		return appErr
+	}
+	return p.updatePrefsUsers(channelID, userID, true)
+}
+
+// getPrefsUsers reads the IDs of the users, who set standing answers in the given channel
+func (p *KickerPlugin) getPrefsUsers(channelID string) ([]string, *model.AppError) {
+	userIDs := []string{}
+	data, appErr := p.API.KVGet(prefsChannelKey(channelID))
+	if appErr != nil {
+		return nil, appErr
+	}
+	if data != nil {
+		if err := json.Unmarshal(data, &userIDs); err != nil {
+			return nil, appError("failed to parse prefs users", err)
+		}
+	}
+	return userIDs, nil
+}
+
+// updatePrefsUsers adds the given user to the users with standing answers in the given channel, or removes the user.
+// The prefs must be locked.
+func (p *KickerPlugin) updatePrefsUsers(channelID, userID string, add bool) *model.AppError {
+	userIDs, appErr := p.getPrefsUsers(channelID)
+	if appErr != nil {
+		return appErr
+	}
+
+	updated := []string{}
+	for _, id := range userIDs {
+		if id != userID {
+			updated = append(updated, id)
+		}
+	}
+	if add {
+		updated = append(updated, userID)
+	}
+	if len(updated) == 0 {
+		return p.API.KVDelete(prefsChannelKey(channelID))
+	}
+
+	data, err := json.Marshal(updated)
+	if err != nil {
+		return appError("failed to serialize prefs users", err)
+	}
+	return p.API.KVSet(prefsChannelKey(channelID), data)
+}
+
+// resolvePrefs returns the members of the given channel, who set a standing answer in it for the weekday
+// of the given start (in their timezone), as automatic players. It reads the users from the KV store and the API,
+// so it is called before the game is locked.
+func (p *KickerPlugin) resolvePrefs(channelID string, start time.Time) []Player {
+	userIDs, appErr := p.getPrefsUsers(channelID)
+	if appErr != nil {
+		p.API.LogError("failed to get prefs users", "channel_id", channelID, "err", appErr.Error())
+		return nil
+	}
+
+	location := p.getConfiguration().location
+	players := []Player{}
+	for _, userID := range userIDs {
+		prefs, prefsErr := p.getPrefs(channelID, userID)
+		if prefsErr != nil {
+			p.API.LogError("failed to get prefs", "user_id", userID, "err", prefsErr.Error())
+			continue
+		}
+
+		user, userErr := p.API.GetUser(userID)
+		if userErr != nil {
+			p.API.LogError("failed to get user of prefs", "user_id", userID, "err", userErr.Error())
+			continue
+		}
+
+		wantLevel, ok := prefs[start.In(userLocation(user, location)).Weekday()]
+		if !ok {
+			continue
+		}
+		if _, memberErr := p.API.GetChannelMember(channelID, userID); memberErr != nil {
+			continue
+		}
+		players = append(players, Player{user: user, wantLevel: wantLevel, automatic: true})
+	}
+	return players
+}
+
+// formatPrefs lists the standing answers with their weekdays, one per line
+func formatPrefs(prefs Prefs, tr translateFunc) string {
+	lines := []string{}
+	for _, answer := range prefAnswerOrder {
+		weekdays := []time.Weekday{}
+		for day, wantLevel := range prefs {
+			if wantLevel == prefAnswers[answer] {
+				weekdays = append(weekdays, day)
+			}
+		}
+		if len(weekdays) > 0 {
+			lines = append(lines, "- "+tr("prefs."+answer, formatWeekdays(weekdays, tr)))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// executePrefsCommand shows the standing answers of the user in the channel, or sets them for the given weekdays
+func (p *KickerPlugin) executePrefsCommand(args *model.CommandArgs, params []string) (*model.CommandResponse, *model.AppError) {
+	tr := p.userTranslator(args.UserId)
+
+	p.prefsLock.Lock()
+	defer p.prefsLock.Unlock()
+	prefs, appErr := p.getPrefs(args.ChannelId, args.UserId)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	if len(params) == 0 {
+		if len(prefs) == 0 {
+			return ephemeralResponse(tr("prefs.empty", p.getConfiguration().Trigger)), nil
+		}
+		return ephemeralResponse(tr("prefs.title") + "\n" + formatPrefs(prefs, tr)), nil
+	}
+
+	if len(params) != 2 {
+		return ephemeralResponse(tr("command.prefs.usage", p.getConfiguration().Trigger)), nil
+	}
+	weekdays, ok := parseWeekdays(params[0])
+	if !ok {
+		return ephemeralResponse(tr("schedule.weekdays_invalid", params[0])), nil
+	}
+	answer := strings.ToLower(params[1])
+	wantLevel, ok := prefAnswers[answer]
+	if !ok && answer != "none" {
+		return ephemeralResponse(tr("command.prefs.usage", p.getConfiguration().Trigger)), nil
+	}
+
+	for _, day := range weekdays {
+		if ok {
+			prefs[day] = wantLevel
+		} else {
+			delete(prefs, day)
+		}
+	}
+	if appErr = p.savePrefs(args.ChannelId, args.UserId, prefs); appErr != nil {
+		return nil, appErr
+	}
+
+	if len(prefs) == 0 {
+		return ephemeralResponse(tr("prefs.empty", p.getConfiguration().Trigger)), nil
+	}
+	return ephemeralResponse(tr("prefs.title") + "\n" + formatPrefs(prefs, tr)), nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+func TestPrefsCommand(t *testing.T) {
+	api := newFakeAPI()
+	p := newTestPlugin(api, loadTestTranslations(t))
+	args := &model.CommandArgs{UserId: "1", ChannelId: "channel"}
+
+	execute := func(command string) string {
+		response, appErr := p.executePrefsCommand(args, strings.Fields(command))
+		if appErr != nil {
+			t.Fatalf("Command %q failed: %s", command, appErr.Error())
+		}
+		return response.Text
+	}
+
+	if text := execute(""); !strings.Contains(text, "no standing answers") {
+		t.Errorf("User without prefs should get a hint, got: %s", text)
+	}
+
+	execute("mon-fri volunteer")
+	execute("tue participate")
+	text := execute("fri decline")
+	for _, expected := range []string{"- Tue: you are in\n", "- Mon, Wed, Thu: you volunteer\n", "- Fri: you decline"} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Prefs should contain %q, got: %s", expected, text)
+		}
+	}
+
+	for _, command := range []string{"tue", "tue maybe", "someday participate"} {
+		if text = execute(command); !strings.Contains(text, "Please use") && !strings.Contains(text, "weekdays") {
+			t.Errorf("Invalid command %q should be rejected, got: %s", command, text)
+		}
+	}
+
+	if text = execute("mon-fri none"); !strings.Contains(text, "no standing answers") {
+		t.Errorf("Prefs should be removed, got: %s", text)
+	}
+	if _, ok := api.kv[prefsKey("channel", "1")]; ok {
+		t.Errorf("Empty prefs should be deleted from the KV store")
+	}
+	if _, ok := api.kv[prefsChannelKey("channel")]; ok {
+		t.Errorf("User without prefs should be removed from the channel")
+	}
+}
+
+func TestApplyPrefs(t *testing.T) {
+	api := newFakeAPI()
+	p := newTestPlugin(api, loadTestTranslations(t))
+
+	p.executePrefsCommand(&model.CommandArgs{UserId: "1", ChannelId: "channel"}, []string{"tue", "participate"})
+	p.executePrefsCommand(&model.CommandArgs{UserId: "2", ChannelId: "channel"}, []string{"mon-fri", "decline"})
+	p.executePrefsCommand(&model.CommandArgs{UserId: "3", ChannelId: "channel"}, []string{"wed", "participate"})
+	// standing answers only apply in the channel, in which they were set
+	p.executePrefsCommand(&model.CommandArgs{UserId: "4", ChannelId: "other"}, []string{"tue", "participate"})
+	p.executePrefsCommand(&model.CommandArgs{UserId: "1", ChannelId: "other"}, []string{"tue", "decline"})
+
+	game := NewGame("creator", "channel", "")
+	game.endTime = time.Date(2019, time.June, 4, 10, 0, 0, 0, time.UTC) // Tuesday
+	game.locale = "en"
+	game.teamSize = 2
+	players := p.resolvePrefs(game.channelID, game.endTime)
+	game.lock.Lock()
+	defer game.lock.Unlock()
+
+	for _, player := range players {
+		game.setAutomaticPlayer(player.user, player.wantLevel)
+	}
+	participants, decliners := playerIDs(game.GetParticipants()), playerIDs(game.GetDecliners())
+	if len(game.participants) != 2 || len(participants) != 1 || participants[0] != "1" || len(decliners) != 1 || decliners[0] != "2" {
+		t.Fatalf("Standing answers for Tuesday should be applied, got: %+v", game.participants)
+	}
+	for _, player := range game.participants {
+		if !player.automatic {
+			t.Errorf("Answer of %s should be marked as automatic", player.user.Id)
+		}
+	}
+
+	if text := p.buildParticipantsAttachment(game).Text; !strings.Contains(text, "👍: user1 (auto)\n") {
+		t.Errorf("Automatic answers should be marked in the poll, got: %s", text)
+	}
+
+	user, _ := api.GetUser("1")
+	game.setPlayer(user, WLVolunteer)
+	if text := p.buildParticipantsAttachment(game).Text; !strings.Contains(text, "👉: user1\n") {
+		t.Errorf("Answering the poll should replace the automatic answer, got: %s", text)
+	}
+}
+
+func TestPrefsPerChannel(t *testing.T) {
+	api := newFakeAPI()
+	p := newTestPlugin(api, loadTestTranslations(t))
+	tuesday := time.Date(2019, time.June, 4, 10, 0, 0, 0, time.UTC)
+	friday := tuesday.AddDate(0, 0, 3)
+
+	p.executePrefsCommand(&model.CommandArgs{UserId: "1", ChannelId: "a"}, []string{"tue", "participate"})
+	p.executePrefsCommand(&model.CommandArgs{UserId: "1", ChannelId: "b"}, []string{"fri", "decline"})
+
+	if players := p.resolvePrefs("a", friday); len(players) != 0 {
+		t.Errorf("Answers of another channel should not apply, got: %v", playerIDs(players))
+	}
+	if players := p.resolvePrefs("a", tuesday); len(players) != 1 || players[0].wantLevel != WLParticipate {
+		t.Errorf("Answers of the channel should apply, got: %+v", players)
+	}
+
+	response, _ := p.executePrefsCommand(&model.CommandArgs{UserId: "1", ChannelId: "b"}, []string{"mon-fri", "none"})
+	if !strings.Contains(response.Text, "no standing answers") {
+		t.Errorf("Prefs of the channel should be removed, got: %s", response.Text)
+	}
+	if players := p.resolvePrefs("a", tuesday); len(players) != 1 {
+		t.Errorf("Removing the answers of one channel should keep the others, got: %v", playerIDs(players))
+	}
+	if response, _ = p.executePrefsCommand(&model.CommandArgs{UserId: "1", ChannelId: "a"}, nil); !strings.Contains(response.Text, "Tue: you are in") {
+		t.Errorf("Prefs should be listed per channel, got: %s", response.Text)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const (
+	// ratingKeyPrefix prefixes the KV store keys of player ratings, followed by the user-ID
+	ratingKeyPrefix = "rating_"
+	// initialRating is the Elo rating of a player without any reported match
+	initialRating = 1000.0
+	// ratingFactor (the Elo K-factor) is the maximum rating change per match
+	ratingFactor = 32.0
+)
+
+// Rating is the Elo skill rating of a Mattermost user
+type Rating struct {
+	UserID string  `json:"user_id"`
+	Value  float64 `json:"value"`
+	Games  int     `json:"games"`
+}
+
+// NewRating returns the initial rating for the given user
+func NewRating(userID string) *Rating {
+	return &Rating{
+		UserID: userID,
+		Value:  initialRating,
+	}
+}
+
+// expectedScore returns the probability of a team with rating a to win against a team with rating b
+func expectedScore(a, b float64) float64 {
+	return 1 / (1 + math.Pow(10, (b-a)/400))
+}
+
+// teamRating returns the rating of a team, which is the average rating of its players
+func teamRating(team []string, ratings map[string]*Rating) float64 {
+	if len(team) == 0 {
+		return initialRating
+	}
+	sum := 0.0
+	for _, userID := range team {
+		sum += ratings[userID].Value
+	}
+	return sum / float64(len(team))
+}
+
+// applyMatchRating updates the ratings of all players of the given reported match.
+// The ratings map must contain a rating for every player.
+func applyMatchRating(match *Match, ratings map[string]*Rating) {
+	winner := match.Winner()
+	if winner < 0 {
+		return
+	}
+
+	teamRatings := [2]float64{
+		teamRating(match.Teams[0], ratings),
+		teamRating(match.Teams[1], ratings),
+	}
+
+	for i, team := range match.Teams {
+		score := 0.0
+		if i == winner {
+			score = 1
+		}
+		delta := ratingFactor * (score - expectedScore(teamRatings[i], teamRatings[1-i]))
+		for _, userID := range team {
+			ratings[userID].Value += delta
+			ratings[userID].Games++
+		}
+	}
+}
+
+func ratingKey(userID string) string {
+	return ratingKeyPrefix + userID
+}
+
+// getRating reads the rating of the given user from the KV store, or returns the initial rating
+func (p *KickerPlugin) getRating(userID string) (*Rating, *model.AppError) {
+	data, appErr := p.API.KVGet(ratingKey(userID))
+	if appErr != nil {
+		return nil, appErr
+	}
+	if data == nil {
+		return NewRating(userID), nil
+	}
+
+	var rating Rating
+	if err := json.Unmarshal(data, &rating); err != nil {
+		return nil, appError("failed to parse rating", err)
+	}
+	return &rating, nil
+}
+
+// getRatings returns the ratings of the given users, keyed by user-ID
+func (p *KickerPlugin) getRatings(userIDs []string) (map[string]*Rating, *model.AppError) {
+	ratings := map[string]*Rating{}
+	for _, userID := range userIDs {
+		rating, appErr := p.getRating(userID)
+		if appErr != nil {
+			return nil, appErr
+		}
+		ratings[userID] = rating
+	}
+	return ratings, nil
+}
+
+// updateRatings applies the result of the given match to the stored ratings of its players
+func (p *KickerPlugin) updateRatings(match *Match) *model.AppError {
+	p.ratingsLock.Lock()
+	defer p.ratingsLock.Unlock()
+
+	ratings, appErr := p.getRatings(match.Players())
+	if appErr != nil {
+		return appErr
+	}
+
+	applyMatchRating(match, ratings)
+
+	for userID, rating := range ratings {
+		data, err := json.Marshal(rating)
+		if err != nil {
+			return appError("failed to serialize rating", err)
+		}
+		if appErr = p.API.KVSet(ratingKey(userID), data); appErr != nil {
+			return appErr
+		}
+	}
+	return nil
+}
+
+// playerIDs returns the user-IDs of the given players
+func playerIDs(players []Player) []string {
+	userIDs := []string{}
+	for _, player := range players {
+		userIDs = append(userIDs, player.user.Id)
+	}
+	return userIDs
+}
+
+// executeRatingCommand shows the rating of the invoking or the given user
+func (p *KickerPlugin) executeRatingCommand(args *model.CommandArgs, params []string) (*model.CommandResponse, *model.AppError) {
+	tr := p.userTranslator(args.UserId)
+	userID := args.UserId
+	if len(params) > 0 {
+		user, appErr := p.API.GetUserByUsername(strings.TrimPrefix(params[0], "@"))
+		if appErr != nil {
+			return ephemeralResponse(tr("rating.user_not_found", params[0])), nil
+		}
+		userID = user.Id
+	}
+
+	user, appErr := p.API.GetUser(userID)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	rating, appErr := p.getRating(userID)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	return ephemeralResponse(tr("rating.show", user.Username, rating.Value, rating.Games)), nil
+}
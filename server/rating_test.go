@@ -0,0 +1,58 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestExpectedScore(t *testing.T) {
+	if e := expectedScore(1000, 1000); e != 0.5 {
+		t.Errorf("Expected score of equal ratings was incorrect, got: %f, want: %f", e, 0.5)
+	}
+
+	if e := expectedScore(1400, 1000); math.Abs(e-0.909) > 0.001 {
+		t.Errorf("Expected score of a 400 points better team was incorrect, got: %f, want: %f", e, 0.909)
+	}
+}
+
+func TestApplyMatchRating(t *testing.T) {
+	ratings := map[string]*Rating{
+		"1": {UserID: "1", Value: 1100},
+		"2": {UserID: "2", Value: 900},
+		"3": NewRating("3"),
+		"4": NewRating("4"),
+	}
+	match := &Match{Teams: [2][]string{{"1", "2"}, {"3", "4"}}}
+
+	// unreported matches do not change ratings
+	applyMatchRating(match, ratings)
+	if ratings["1"].Value != 1100 || ratings["1"].Games != 0 {
+		t.Errorf("Rating changed for a match without result: %+v", ratings["1"])
+	}
+
+	match.Score = [2]int{10, 4}
+	match.Reported = true
+	applyMatchRating(match, ratings)
+
+	// both teams have an average rating of 1000, so the winners gain half of the rating factor
+	if ratings["1"].Value != 1116 || ratings["2"].Value != 916 {
+		t.Errorf("Ratings of winners were incorrect, got: %f, %f, want: %f, %f", ratings["1"].Value, ratings["2"].Value, 1116.0, 916.0)
+	}
+	if ratings["3"].Value != 984 || ratings["4"].Value != 984 {
+		t.Errorf("Ratings of losers were incorrect, got: %f, %f, want: %f", ratings["3"].Value, ratings["4"].Value, 984.0)
+	}
+	if ratings["4"].Games != 1 {
+		t.Errorf("Number of games was incorrect, got: %d, want: %d", ratings["4"].Games, 1)
+	}
+}
+
+func TestJoinPlayerNamesWithRatings(t *testing.T) {
+	ratings := map[string]*Rating{
+		"1": {UserID: "1", Value: 1015.6},
+	}
+
+	r := JoinPlayerNamesWithRatings([]Player{*horst, *baerbel}, ratings)
+	if r != "horst (1016), bärbel" {
+		t.Errorf("Concatenated usernames were incorrect, got: %s, want: %s", r, "horst (1016), bärbel")
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const (
+	// recruitKeyPrefix prefixes the KV store keys of the recruit settings per channel, followed by the channel-ID
+	recruitKeyPrefix = "recruit_"
+	// recruitPeriod is the period, in which the matches of the regulars are counted
+	recruitPeriod = 28 * 24 * time.Hour
+	// recruitHistoryCount limits the number of recent matches, in which the regulars are searched
+	recruitHistoryCount = 200
+
+	// recruitOff disables the recruiting
+	recruitOff = "off"
+	// recruitMention mentions the regulars below the warning post
+	recruitMention = "mention"
+	// recruitDirect sends a direct message to each regular
+	recruitDirect = "dm"
+)
+
+// recruitModes are the valid modes of recruitSettings
+var recruitModes = []string{recruitOff, recruitMention, recruitDirect}
+
+// recruitSettings define how the regulars of a channel are recruited, if a poll lacks players
+type recruitSettings struct {
+	Mode  string `json:"mode"`  // see recruitModes
+	Limit int    `json:"limit"` // maximum number of regulars pinged per poll
+}
+
+func recruitKey(channelID string) string {
+	return recruitKeyPrefix + channelID
+}
+
+// isRecruitMode checks if the given mode is one of recruitModes
+func isRecruitMode(mode string) bool {
+	for _, m := range recruitModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// findRegulars returns the users, who played most in the given matches (newest first) since the given time,
+// the most recent first among equals. Users in the skip set are left out, at most limit users are returned.
+func findRegulars(matches []*Match, since time.Time, skip map[string]bool, limit int) []string {
+	games := map[string]int{}
+	// lastPlayed is the index of the newest match of the user, lower is more recent
+	lastPlayed := map[string]int{}
+	userIDs := []string{}
+	for i, match := range matches {
+		if match.StartTime.Before(since) {
+			continue
+		}
+		for _, userID := range match.Players() {
+			if skip[userID] {
+				continue
+			}
+			if _, ok := games[userID]; !ok {
+				lastPlayed[userID] = i
+				userIDs = append(userIDs, userID)
+			}
+			games[userID]++
+		}
+	}
+
+	sort.Slice(userIDs, func(i, j int) bool {
+		a, b := userIDs[i], userIDs[j]
+		if games[a] != games[b] {
+			return games[a] > games[b]
+		}
+		if lastPlayed[a] != lastPlayed[b] {
+			return lastPlayed[a] < lastPlayed[b]
+		}
+		return a < b
+	})
+
+	if len(userIDs) > limit {
+		userIDs = userIDs[:limit]
+	}
+	return userIDs
+}
+
+// recruitState describes the given recruit settings for the user
+func recruitState(settings recruitSettings, tr translateFunc) string {
+	if settings.Mode == recruitOff {
+		return tr("command.recruit.state.off")
+	}
+	return tr("command.recruit.state."+settings.Mode, settings.Limit)
+}
+
+// getRecruitSettings returns the recruit settings of the given channel, or the configured defaults.
+// The limit never exceeds the configured one.
+func (p *KickerPlugin) getRecruitSettings(channelID string) recruitSettings {
+	configuration := p.getConfiguration()
+	settings := recruitSettings{Mode: configuration.RecruitMode, Limit: configuration.recruitLimit}
+
+	data, appErr := p.API.KVGet(recruitKey(channelID))
+	if appErr != nil {
+		p.API.LogError("failed to get recruit settings", "channel_id", channelID, "err", appErr.Error())
+		return settings
+	}
+	if data != nil {
+		if err := json.Unmarshal(data, &settings); err != nil {
+			p.API.LogError("failed to parse recruit settings", "channel_id", channelID, "err", err.Error())
+		}
+	}
+	if settings.Limit > configuration.recruitLimit {
+		settings.Limit = configuration.recruitLimit
+	}
+	return settings
+}
+
+// recruitHistory returns the matches of the recruit period in the given channel, in which the regulars are searched,
+// or nil if the channel does not recruit. It reads every match from the KV store, so it is called before the game is locked.
+func (p *KickerPlugin) recruitHistory(channelID string) []*Match {
+	if settings := p.getRecruitSettings(channelID); settings.Mode == recruitOff || settings.Limit < 1 {
+		return nil
+	}
+
+	matches, appErr := p.getRecentMatches(channelID, recruitHistoryCount, time.Now().Add(-recruitPeriod))
+	if appErr != nil {
+		p.API.LogError("failed to get match history", "channel_id", channelID, "err", appErr.Error())
+		return nil
+	}
+	return matches
+}
+
+// recruitRegulars finds the regulars in the given matches (see recruitHistory) of the channel of the given game,
+// who did not answer the poll yet. In mention mode, the returned text mentions them, to be appended to the warning post.
+// In direct mode, they get a direct message and an empty text is returned.
+// The game must be locked.
+func (p *KickerPlugin) recruitRegulars(game *Game, matches []*Match, missing int) string {
+	settings := p.getRecruitSettings(game.channelID)
+	if settings.Mode == recruitOff || settings.Limit < 1 || len(matches) == 0 {
+		return ""
+	}
+
+	// everybody who answered is left out, in particular who declined
+	answered := map[string]bool{p.botUserID: true}
+	for _, player := range game.participants {
+		answered[player.user.Id] = true
+	}
+	regulars := findRegulars(matches, time.Now().Add(-recruitPeriod), answered, settings.Limit)
+	if len(regulars) == 0 {
+		return ""
+	}
+
+	if settings.Mode == recruitMention {
+		mentions := p.getUsernames(regulars, map[string]string{})
+		return p.translations.translate(game.locale, "recruit.mention", "@"+strings.Join(mentions, ", @"), missing)
+	}
+
+	channelName := p.channelName(game.channelID)
+	for _, userID := range regulars {
+		tr := p.userTranslator(userID)
+		p.sendReminder(userID, tr("recruit.direct", channelName, formatRelativeDuration(time.Until(game.endTime), tr), missing))
+	}
+	return ""
+}
+
+// executeRecruitCommand shows how the regulars of the channel are recruited, if a poll lacks players,
+// or lets admins set it. The limit is capped by the configured one.
+func (p *KickerPlugin) executeRecruitCommand(args *model.CommandArgs, params []string) (*model.CommandResponse, *model.AppError) {
+	tr := p.userTranslator(args.UserId)
+	settings := p.getRecruitSettings(args.ChannelId)
+	if len(params) == 0 {
+		return ephemeralResponse(recruitState(settings, tr)), nil
+	}
+	if !p.canManage(args.UserId, "", args.ChannelId) {
+		return ephemeralResponse(tr("command.recruit.not_allowed")), nil
+	}
+
+	configuration := p.getConfiguration()
+	usage := ephemeralResponse(tr("command.recruit.usage", configuration.Trigger))
+	if len(params) > 2 || !isRecruitMode(strings.ToLower(params[0])) {
+		return usage, nil
+	}
+	settings.Mode = strings.ToLower(params[0])
+	if len(params) == 2 {
+		limit, err := strconv.Atoi(params[1])
+		if err != nil || limit < 1 {
+			return usage, nil
+		}
+		settings.Limit = limit
+	}
+	if settings.Limit > configuration.recruitLimit {
+		settings.Limit = configuration.recruitLimit
+	}
+
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return nil, appError("failed to serialize recruit settings", err)
+	}
+	if appErr := p.API.KVSet(recruitKey(args.ChannelId), data); appErr != nil {
+		return nil, appErr
+	}
+	return ephemeralResponse(recruitState(settings, tr)), nil
+}
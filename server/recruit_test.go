@@ -0,0 +1,87 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+func TestFindRegulars(t *testing.T) {
+	now := time.Now()
+	matches := []*Match{
+		{Teams: [2][]string{{"1"}, {"2"}}, StartTime: now.Add(-time.Hour)},
+		{Teams: [2][]string{{"3"}, {"4"}}, StartTime: now.Add(-2 * time.Hour)},
+		{Teams: [2][]string{{"1"}, {"3"}}, StartTime: now.Add(-3 * time.Hour)},
+		{Teams: [2][]string{{"5"}, {"5"}}, StartTime: now.Add(-recruitPeriod - time.Hour)},
+	}
+
+	regulars := findRegulars(matches, now.Add(-recruitPeriod), map[string]bool{}, 10)
+	if strings.Join(regulars, ",") != "1,3,2,4" {
+		t.Errorf("Regulars should be ordered by games and recency, got: %v", regulars)
+	}
+
+	regulars = findRegulars(matches, now.Add(-recruitPeriod), map[string]bool{"1": true}, 2)
+	if strings.Join(regulars, ",") != "3,2" {
+		t.Errorf("Regulars should be limited and skipped, got: %v", regulars)
+	}
+}
+
+func TestCheckEnoughPlayerRecruits(t *testing.T) {
+	api := newFakeAPI()
+	p := newTestPlugin(api, loadTestTranslations(t))
+
+	for i := 0; i < 3; i++ {
+		teams := [2][]Player{{*horst, *baerbel}, {*etienne, *ingebork}}
+		if i > 0 {
+			teams[1] = []Player{*kay, *oke}
+		}
+		if appErr := p.addMatch(NewMatch("channel", teams, time.Now().Add(-time.Duration(i)*time.Hour))); appErr != nil {
+			t.Fatalf("Failed to store match: %s", appErr.Error())
+		}
+	}
+
+	game := startTestGame(p, "channel", time.Hour)
+	defer p.CreateEndPollPost(game)
+	if err := p.setUserWantLevel(game, "1", WLParticipate); err != nil {
+		t.Fatalf("Click failed: %s", err)
+	}
+	if err := p.setUserWantLevel(game, "2", WLDecline); err != nil {
+		t.Fatalf("Click failed: %s", err)
+	}
+
+	if response, _ := p.executeRecruitCommand(&model.CommandArgs{UserId: "1", ChannelId: "channel"}, []string{"off"}); !strings.Contains(response.Text, "Only admins") {
+		t.Errorf("Users should not change the recruit settings, got: %s", response.Text)
+	}
+
+	api.admins["admin"] = true
+	args := &model.CommandArgs{UserId: "admin", ChannelId: "channel"}
+	if response, _ := p.executeRecruitCommand(args, []string{"mention", "0"}); !strings.Contains(response.Text, "Please use") {
+		t.Errorf("Limit below 1 should be rejected, got: %s", response.Text)
+	}
+	if response, _ := p.executeRecruitCommand(args, []string{"mention", "100"}); !strings.Contains(response.Text, "up to 5 regulars") {
+		t.Errorf("Limit should be capped by the configuration, got: %s", response.Text)
+	}
+	if response, _ := p.executeRecruitCommand(args, []string{"mention", "2"}); !strings.Contains(response.Text, "up to 2 regulars") {
+		t.Errorf("Recruit settings were not changed, got: %s", response.Text)
+	}
+
+	p.CheckEnoughPlayer(game)
+	posts := api.createdPosts()
+	if len(posts) != 1 || !strings.HasSuffix(posts[0], "\n@user5, @user6, we still need 3 more! Who is in?") {
+		t.Errorf("Warning should mention the regulars, who did not answer, got: %v", posts)
+	}
+
+	p.executeRecruitCommand(args, []string{"dm"})
+	p.CheckEnoughPlayer(game)
+	if messages := api.directMessages(); len(messages) != 2 || len(messages["5"]) != 1 || len(messages["6"]) != 1 {
+		t.Errorf("Regulars should get a direct message, got: %v", messages)
+	}
+
+	p.executeRecruitCommand(args, []string{"off"})
+	p.CheckEnoughPlayer(game)
+	if posts = api.createdPosts(); len(posts) != 3 || strings.Contains(posts[2], "@") {
+		t.Errorf("Nobody should be recruited, got: %v", posts)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// remindersOffKeyPrefix prefixes the KV store keys of the users, who turned off the reminders, followed by the user-ID
+const remindersOffKeyPrefix = "reminders_off_"
+
+// teamNames are the names of the teams of a match, as in the result post
+var teamNames = [2]string{"A", "B"}
+
+func remindersOffKey(userID string) string {
+	return remindersOffKeyPrefix + userID
+}
+
+// wantsReminders checks if the given user did not turn off the reminders
+func (p *KickerPlugin) wantsReminders(userID string) bool {
+	data, appErr := p.API.KVGet(remindersOffKey(userID))
+	if appErr != nil {
+		p.API.LogError("failed to get reminder setting", "user_id", userID, "err", appErr.Error())
+		return true
+	}
+	return data == nil
+}
+
+// sendReminder sends the given message from the bot to the given user by direct message,
+// unless the user turned off the reminders
+func (p *KickerPlugin) sendReminder(userID, message string) {
+	if !p.wantsReminders(userID) {
+		return
+	}
+
+	channel, appErr := p.API.GetDirectChannel(p.botUserID, userID)
+	if appErr != nil {
+		p.API.LogError("failed to get direct channel", "user_id", userID, "err", appErr.Error())
+		return
+	}
+
+	if _, appErr = p.API.CreatePost(&model.Post{
+		UserId:    p.botUserID,
+		ChannelId: channel.Id,
+		Message:   message,
+		Type:      model.POST_DEFAULT,
+	}); appErr != nil {
+		p.API.LogError("failed to send reminder", "user_id", userID, "err", appErr.Error())
+	}
+}
+
+// sendReminders reminds the players signed up for the given game of its upcoming start
+func (p *KickerPlugin) sendReminders(game *Game) {
+	game.lock.Lock()
+	defer game.lock.Unlock()
+	if game.ended {
+		return
+	}
+
+	channelName := p.channelName(game.channelID)
+	for _, player := range game.participants {
+		messageID := "reminder.participate"
+		switch player.wantLevel {
+		case WLVolunteer:
+			messageID = "reminder.volunteer"
+		case WLDecline:
+			continue
+		}
+
+		tr := p.translations.translator(player.user.Locale)
+		p.sendReminder(player.user.Id, tr(messageID, channelName, formatRelativeDuration(time.Until(game.endTime), tr)))
+	}
+}
+
+// sendStartReminders tells the players of the given matches, that the table is free, with whom and against whom they play.
+// If only is not nil, just the users in it are told.
+func (p *KickerPlugin) sendStartReminders(channelID string, matches []*Match, only map[string]bool) {
+	channelName := p.channelName(channelID)
+	cache := map[string]*model.User{}
+
+	for i, match := range matches {
+		for team, userIDs := range match.Teams {
+			opponents := JoinPlayerNames(p.getPlayers(match.Teams[1-team], cache))
+			for _, userID := range userIDs {
+				if only != nil && !only[userID] {
+					continue
+				}
+				mateIDs := []string{}
+				for _, id := range userIDs {
+					if id != userID {
+						mateIDs = append(mateIDs, id)
+					}
+				}
+
+				user := p.getPlayers([]string{userID}, cache)[0].user
+				tr := p.translations.translator(user.Locale)
+				message := tr("reminder.start_single", teamNames[team], opponents, channelName)
+				if len(mateIDs) > 0 {
+					message = tr("reminder.start", teamNames[team], JoinPlayerNames(p.getPlayers(mateIDs, cache)), opponents, channelName)
+				}
+				if len(matches) > 1 {
+					message = tr("game.table", i+1, message)
+				}
+				p.sendReminder(userID, message)
+			}
+		}
+	}
+}
+
+// executeRemindersCommand turns the reminders by direct message on or off for the user
+func (p *KickerPlugin) executeRemindersCommand(args *model.CommandArgs, params []string) (*model.CommandResponse, *model.AppError) {
+	tr := p.userTranslator(args.UserId)
+	if len(params) == 0 {
+		if p.wantsReminders(args.UserId) {
+			return ephemeralResponse(tr("command.reminders.on")), nil
+		}
+		return ephemeralResponse(tr("command.reminders.off")), nil
+	}
+
+	switch params[0] {
+	case "on":
+		if appErr := p.API.KVDelete(remindersOffKey(args.UserId)); appErr != nil {
+			return nil, appErr
+		}
+		return ephemeralResponse(tr("command.reminders.on")), nil
+	case "off":
+		if appErr := p.API.KVSet(remindersOffKey(args.UserId), []byte("true")); appErr != nil {
+			return nil, appErr
+		}
+		return ephemeralResponse(tr("command.reminders.off")), nil
+	}
+	return ephemeralResponse(tr("command.reminders.usage", p.getConfiguration().Trigger)), nil
+}
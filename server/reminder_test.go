@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+func TestSendReminders(t *testing.T) {
+	api := newFakeAPI()
+	p := newTestPlugin(api, loadTestTranslations(t))
+
+	game := startTestGame(p, "channel", time.Hour)
+	defer p.CreateEndPollPost(game)
+	for userID, wantLevel := range map[string]WantLevel{"1": WLParticipate, "2": WLVolunteer, "3": WLDecline, "4": WLParticipate} {
+		if err := p.setUserWantLevel(game, userID, wantLevel); err != nil {
+			t.Fatalf("Click failed: %s", err)
+		}
+	}
+
+	response, _ := p.executeRemindersCommand(&model.CommandArgs{UserId: "4"}, []string{"off"})
+	if !strings.Contains(response.Text, "no direct messages") {
+		t.Errorf("Reminders were not turned off, got: %s", response.Text)
+	}
+
+	p.sendReminders(game)
+
+	messages := api.directMessages()
+	if len(messages) != 2 {
+		t.Fatalf("Only signed up players with reminders should be reminded, got: %v", messages)
+	}
+	if len(messages["1"]) != 1 || !strings.HasPrefix(messages["1"][0], "Kicker in ~channel-channel starts in 1 hour, you are in!") {
+		t.Errorf("Participant was not reminded, got: %v", messages["1"])
+	}
+	if len(messages["2"]) != 1 || !strings.Contains(messages["2"][0], "as volunteer") {
+		t.Errorf("Volunteer was not reminded, got: %v", messages["2"])
+	}
+}
+
+func TestSendStartReminders(t *testing.T) {
+	api := newFakeAPI()
+	p := newTestPlugin(api, loadTestTranslations(t))
+
+	game := startTestGame(p, "channel", time.Hour)
+	for _, userID := range []string{"1", "2", "3", "4", "5"} {
+		if err := p.setUserWantLevel(game, userID, WLParticipate); err != nil {
+			t.Fatalf("Click failed: %s", err)
+		}
+	}
+	p.executeRemindersCommand(&model.CommandArgs{UserId: "2"}, []string{"off"})
+	p.executeRemindersCommand(&model.CommandArgs{UserId: "2"}, []string{"on"})
+	p.CreateEndPollPost(game)
+
+	messages := api.directMessages()
+	if len(messages) != 4 {
+		t.Fatalf("Every chosen player should be notified, got: %v", messages)
+	}
+	for userID, userMessages := range messages {
+		if len(userMessages) != 1 || !strings.HasPrefix(userMessages[0], "The table is yours! You play in Team ") || strings.Contains(userMessages[0], "user"+userID) {
+			t.Errorf("Player %s was not notified correctly, got: %v", userID, userMessages)
+		}
+	}
+}
@@ -0,0 +1,483 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const (
+	// scheduleKeyPrefix prefixes the KV store keys of the schedules, followed by the schedule-ID
+	scheduleKeyPrefix = "schedule_"
+	// scheduleIndexKey is the KV store key of the IDs of all schedules, so that they are found without listing all keys
+	scheduleIndexKey = "schedule_index"
+)
+
+// weekdayNames maps the English and German names and abbreviations of the weekdays to the weekdays
+var weekdayNames = map[string]time.Weekday{
+	"mon": time.Monday, "monday": time.Monday, "mo": time.Monday, "montag": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday, "di": time.Tuesday, "dienstag": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday, "mi": time.Wednesday, "mittwoch": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday, "do": time.Thursday, "donnerstag": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday, "fr": time.Friday, "freitag": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday, "sa": time.Saturday, "samstag": time.Saturday,
+	"sun": time.Sunday, "sunday": time.Sunday, "so": time.Sunday, "sonntag": time.Sunday,
+}
+
+// weekdayGroups maps names of several weekdays to the weekdays, Monday first
+var weekdayGroups = map[string][]time.Weekday{
+	"daily":    {time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday},
+	"täglich":  {time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday},
+	"weekdays": {time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+	"werktags": {time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+}
+
+// Schedule defines a recurring game: its poll is started automatically before every start.
+// The start time is interpreted in the timezone of the creator, in whose name the polls are started.
+type Schedule struct {
+	ID        string         `json:"id"`
+	UserID    string         `json:"user_id"`
+	TeamID    string         `json:"team_id"`
+	ChannelID string         `json:"channel_id"`
+	Weekdays  []time.Weekday `json:"weekdays"` // Monday first
+	Hour      int            `json:"hour"`
+	Minute    int            `json:"minute"`
+	CreateAt  time.Time      `json:"create_at"`
+	LastStart time.Time      `json:"last_start"` // start of the last game started by the schedule
+}
+
+func scheduleKey(scheduleID string) string {
+	return scheduleKeyPrefix + scheduleID
+}
+
+// mondayIndex returns the position of the weekday in a week starting on Monday
+func mondayIndex(day time.Weekday) int {
+	return (int(day) + 6) % 7
+}
+
+// parseWeekdays parses weekdays like "mon-fri", "mon,wed,fri" or "daily", and returns them Monday first
+func parseWeekdays(expression string) ([]time.Weekday, bool) {
+	included := [7]bool{}
+	for _, part := range strings.Split(strings.ToLower(expression), ",") {
+		if group, ok := weekdayGroups[part]; ok {
+			for _, day := range group {
+				included[mondayIndex(day)] = true
+			}
+			continue
+		}
+
+		bounds := strings.Split(part, "-")
+		if len(bounds) > 2 {
+			return nil, false
+		}
+		first, ok := weekdayNames[bounds[0]]
+		if !ok {
+			return nil, false
+		}
+		last, ok := weekdayNames[bounds[len(bounds)-1]]
+		if !ok {
+			return nil, false
+		}
+		// ranges may wrap around the weekend, e.g. "fri-mon"
+		for i := mondayIndex(first); ; i = (i + 1) % 7 {
+			included[i] = true
+			if i == mondayIndex(last) {
+				break
+			}
+		}
+	}
+
+	weekdays := []time.Weekday{}
+	for i, ok := range included {
+		if ok {
+			weekdays = append(weekdays, time.Weekday((i+1)%7))
+		}
+	}
+	return weekdays, true
+}
+
+// formatWeekdays returns the weekdays as readable text, e.g. "Mon–Fri" or "Mon, Wed, Fri"
+func formatWeekdays(weekdays []time.Weekday, tr translateFunc) string {
+	included := [7]bool{}
+	for _, day := range weekdays {
+		included[mondayIndex(day)] = true
+	}
+
+	name := func(i int) string {
+		return tr(fmt.Sprintf("weekday.%d", (i+1)%7))
+	}
+
+	parts := []string{}
+	for i := 0; i < 7; i++ {
+		if !included[i] {
+			continue
+		}
+		last := i
+		for last+1 < 7 && included[last+1] {
+			last++
+		}
+		switch {
+		case last-i >= 2:
+			parts = append(parts, name(i)+"–"+name(last))
+		case last > i:
+			parts = append(parts, name(i), name(last))
+		default:
+			parts = append(parts, name(i))
+		}
+		i = last
+	}
+	return strings.Join(parts, ", ")
+}
+
+// hasWeekday checks if the schedule contains the given weekday
+func (s *Schedule) hasWeekday(day time.Weekday) bool {
+	for _, d := range s.Weekdays {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// dueStart returns the start, whose poll should be open at now (in the timezone of the schedule):
+// the next start within the given lead time, which is neither on a holiday nor started already
+func (s *Schedule) dueStart(now time.Time, lead time.Duration, isHoliday func(time.Time) bool) (time.Time, bool) {
+	for i := 0; i <= int(lead/(24*time.Hour))+1; i++ {
+		day := now.AddDate(0, 0, i)
+		start := time.Date(day.Year(), day.Month(), day.Day(), s.Hour, s.Minute, 0, 0, now.Location())
+		if !start.After(now) {
+			continue
+		}
+		if start.Add(-lead).After(now) {
+			break
+		}
+		if start.After(s.LastStart) && s.hasWeekday(start.Weekday()) && !isHoliday(start) {
+			return start, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// saveSchedule writes the given schedule to the KV store. The schedules must be locked.
+func (p *KickerPlugin) saveSchedule(schedule *Schedule) *model.AppError {
+	data, err := json.Marshal(schedule)
+	if err != nil {
+		return appError("failed to serialize schedule", err)
+	}
+	return p.API.KVSet(scheduleKey(schedule.ID), data)
+}
+
+// getSchedule reads the schedule with the given ID from the KV store, or returns nil if it was removed
+func (p *KickerPlugin) getSchedule(scheduleID string) (*Schedule, *model.AppError) {
+	data, appErr := p.API.KVGet(scheduleKey(scheduleID))
+	if appErr != nil || data == nil {
+		return nil, appErr
+	}
+
+	var schedule Schedule
+	if err := json.Unmarshal(data, &schedule); err != nil {
+		return nil, appError("failed to parse schedule", err)
+	}
+	return &schedule, nil
+}
+
+// getScheduleIndex reads the IDs of all schedules from the KV store
+func (p *KickerPlugin) getScheduleIndex() ([]string, *model.AppError) {
+	scheduleIDs := []string{}
+	data, appErr := p.API.KVGet(scheduleIndexKey)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if data != nil {
+		if err := json.Unmarshal(data, &scheduleIDs); err != nil {
+			return nil, appError("failed to parse schedule index", err)
+		}
+	}
+	return scheduleIDs, nil
+}
+
+// saveScheduleIndex writes the IDs of all schedules to the KV store. The schedules must be locked.
+func (p *KickerPlugin) saveScheduleIndex(scheduleIDs []string) *model.AppError {
+	data, err := json.Marshal(scheduleIDs)
+	if err != nil {
+		return appError("failed to serialize schedule index", err)
+	}
+	return p.API.KVSet(scheduleIndexKey, data)
+}
+
+// addSchedule stores the given new schedule and adds it to the index
+func (p *KickerPlugin) addSchedule(schedule *Schedule) *model.AppError {
+	p.schedulesLock.Lock()
+	defer p.schedulesLock.Unlock()
+
+	scheduleIDs, appErr := p.getScheduleIndex()
+	if appErr != nil {
+		return appErr
+	}
+	if appErr = p.saveSchedule(schedule); appErr != nil {
+		return appErr
+	}
+	return p.saveScheduleIndex(append(scheduleIDs, schedule.ID))
+}
+
+// removeSchedule removes the schedule with the given ID from the KV store and the index
+func (p *KickerPlugin) removeSchedule(scheduleID string) *model.AppError {
+	p.schedulesLock.Lock()
+	defer p.schedulesLock.Unlock()
+
+	scheduleIDs, appErr := p.getScheduleIndex()
+	if appErr != nil {
+		return appErr
+	}
+	if appErr = p.API.KVDelete(scheduleKey(scheduleID)); appErr != nil {
+		return appErr
+	}
+
+	remaining := []string{}
+	for _, id := range scheduleIDs {
+		if id != scheduleID {
+			remaining = append(remaining, id)
+		}
+	}
+	return p.saveScheduleIndex(remaining)
+}
+
+// markScheduleStarted stores the given start as the last start of the schedule with the given ID.
+// The schedule is read again, so that a schedule removed in the meantime is not stored again;
+// then false is returned.
+func (p *KickerPlugin) markScheduleStarted(scheduleID string, start time.Time) (bool, *model.AppError) {
+	p.schedulesLock.Lock()
+	defer p.schedulesLock.Unlock()
+
+	schedule, appErr := p.getSchedule(scheduleID)
+	if appErr != nil || schedule == nil {
+		return false, appErr
+	}
+	schedule.LastStart = start
+	if appErr = p.saveSchedule(schedule); appErr != nil {
+		return false, appErr
+	}
+	return true, nil
+}
+
+// getSchedules reads all schedules of the index from the KV store, the oldest first
+func (p *KickerPlugin) getSchedules() ([]*Schedule, *model.AppError) {
+	scheduleIDs, appErr := p.getScheduleIndex()
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	schedules := []*Schedule{}
+	for _, scheduleID := range scheduleIDs {
+		schedule, getErr := p.getSchedule(scheduleID)
+		if getErr != nil {
+			p.API.LogError("failed to get schedule", "schedule_id", scheduleID, "err", getErr.Error())
+			continue
+		}
+		if schedule != nil {
+			schedules = append(schedules, schedule)
+		}
+	}
+
+	sort.Slice(schedules, func(i, j int) bool {
+		return schedules[i].CreateAt.Before(schedules[j].CreateAt)
+	})
+	return schedules, nil
+}
+
+// getTeamSchedules returns the schedules of the channels of the given team, the oldest first
+func (p *KickerPlugin) getTeamSchedules(teamID string) ([]*Schedule, *model.AppError) {
+	schedules, appErr := p.getSchedules()
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	teamSchedules := []*Schedule{}
+	for _, schedule := range schedules {
+		if schedule.TeamID == teamID {
+			teamSchedules = append(teamSchedules, schedule)
+		}
+	}
+	return teamSchedules, nil
+}
+
+// channelName returns the name of the given channel as reference, e.g. "~town-square"
+func (p *KickerPlugin) channelName(channelID string) string {
+	channel, appErr := p.API.GetChannel(channelID)
+	if appErr != nil {
+		return "?"
+	}
+	return "~" + channel.Name
+}
+
+// formatSchedule returns the weekdays, time and channel of the given schedule as readable text
+func (p *KickerPlugin) formatSchedule(schedule *Schedule, tr translateFunc) string {
+	clock := time.Date(2000, time.January, 1, schedule.Hour, schedule.Minute, 0, 0, time.UTC).Format(tr("time.format.clock"))
+	return tr("schedule.entry", formatWeekdays(schedule.Weekdays, tr), clock, p.channelName(schedule.ChannelID))
+}
+
+// executeScheduleCommand adds, lists or removes the recurring games
+func (p *KickerPlugin) executeScheduleCommand(args *model.CommandArgs, params []string) (*model.CommandResponse, *model.AppError) {
+	tr := p.userTranslator(args.UserId)
+	if len(params) > 0 {
+		switch strings.ToLower(params[0]) {
+		case "add":
+			return p.executeScheduleAddCommand(args, params[1:], tr)
+		case "list":
+			return p.executeScheduleListCommand(args, tr)
+		case "remove":
+			return p.executeScheduleRemoveCommand(args, params[1:], tr)
+		}
+	}
+	return ephemeralResponse(tr("command.schedule.usage", p.getConfiguration().Trigger)), nil
+}
+
+// executeScheduleAddCommand creates a schedule from params like "mon-fri 12:30 ~channel"
+func (p *KickerPlugin) executeScheduleAddCommand(args *model.CommandArgs, params []string, tr translateFunc) (*model.CommandResponse, *model.AppError) {
+	configuration := p.getConfiguration()
+	if len(params) < 2 {
+		return ephemeralResponse(tr("command.schedule.usage", configuration.Trigger)), nil
+	}
+
+	weekdays, ok := parseWeekdays(params[0])
+	if !ok {
+		return ephemeralResponse(tr("schedule.weekdays_invalid", params[0])), nil
+	}
+
+	channelID := args.ChannelId
+	clockParams := params[1:]
+	if name := clockParams[len(clockParams)-1]; strings.HasPrefix(name, "~") && len(clockParams) > 1 {
+		channel, appErr := p.API.GetChannelByName(args.TeamId, strings.TrimPrefix(name, "~"), false)
+		if appErr != nil {
+			return ephemeralResponse(tr("schedule.channel_not_found", name)), nil
+		}
+		if _, appErr = p.API.GetChannelMember(channel.Id, args.UserId); appErr != nil {
+			return ephemeralResponse(tr("schedule.channel_not_found", name)), nil
+		}
+		channelID = channel.Id
+		clockParams = clockParams[:len(clockParams)-1]
+	}
+
+	hour, minute, timeErr := parseClock(strings.ToLower(strings.Join(clockParams, " ")))
+	if timeErr != nil {
+		return ephemeralResponse(timeErr.translate(tr)), nil
+	}
+
+	schedule := &Schedule{
+		ID:        model.NewId(),
+		UserID:    args.UserId,
+		TeamID:    args.TeamId,
+		ChannelID: channelID,
+		Weekdays:  weekdays,
+		Hour:      hour,
+		Minute:    minute,
+		CreateAt:  time.Now(),
+	}
+	if appErr := p.addSchedule(schedule); appErr != nil {
+		return nil, appErr
+	}
+
+	return ephemeralResponse(tr("schedule.added", p.formatSchedule(schedule, tr), int(configuration.scheduleLead.Minutes()))), nil
+}
+
+// executeScheduleListCommand lists the schedules of the team, numbered for removal
+func (p *KickerPlugin) executeScheduleListCommand(args *model.CommandArgs, tr translateFunc) (*model.CommandResponse, *model.AppError) {
+	schedules, appErr := p.getTeamSchedules(args.TeamId)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if len(schedules) == 0 {
+		return ephemeralResponse(tr("schedule.list_empty")), nil
+	}
+
+	cache := map[string]string{}
+	text := tr("schedule.list_title")
+	for i, schedule := range schedules {
+		text += fmt.Sprintf("\n%d. %s (%s)", i+1, p.formatSchedule(schedule, tr), p.getUsernames([]string{schedule.UserID}, cache)[0])
+	}
+	return ephemeralResponse(text), nil
+}
+
+// executeScheduleRemoveCommand removes the schedule with the given number of the list
+func (p *KickerPlugin) executeScheduleRemoveCommand(args *model.CommandArgs, params []string, tr translateFunc) (*model.CommandResponse, *model.AppError) {
+	schedules, appErr := p.getTeamSchedules(args.TeamId)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	number := 0
+	if len(params) == 1 {
+		number, _ = strconv.Atoi(params[0])
+	}
+	if number < 1 || number > len(schedules) {
+		return ephemeralResponse(tr("schedule.not_found", p.getConfiguration().Trigger)), nil
+	}
+
+	schedule := schedules[number-1]
+	if !p.canManage(args.UserId, schedule.UserID, schedule.ChannelID) {
+		return ephemeralResponse(tr("schedule.not_allowed")), nil
+	}
+
+	if appErr = p.removeSchedule(schedule.ID); appErr != nil {
+		return nil, appErr
+	}
+	return ephemeralResponse(tr("schedule.removed", p.formatSchedule(schedule, tr))), nil
+}
+
+// startScheduledGames starts the polls of all schedules, which are due at the given time
+func (p *KickerPlugin) startScheduledGames(now time.Time) {
+	schedules, appErr := p.getSchedules()
+	if appErr != nil {
+		p.API.LogError("failed to get schedules", "err", appErr.Error())
+		return
+	}
+
+	configuration := p.getConfiguration()
+	for _, schedule := range schedules {
+		user, userErr := p.API.GetUser(schedule.UserID)
+		if userErr != nil {
+			p.API.LogError("failed to get creator of schedule", "schedule_id", schedule.ID, "err", userErr.Error())
+			continue
+		}
+
+		localNow := now.In(userLocation(user, configuration.location))
+		if start, due := schedule.dueStart(localNow, configuration.scheduleLead, configuration.isHoliday); due {
+			p.startScheduledGame(schedule, start, localNow)
+		}
+	}
+}
+
+// startScheduledGame starts the poll for the given start of the schedule, like the creator would with /kicker start
+func (p *KickerPlugin) startScheduledGame(schedule *Schedule, start, now time.Time) {
+	// mark the start first, so that a failing poll is not retried every minute
+	started, appErr := p.markScheduleStarted(schedule.ID, start)
+	if appErr != nil {
+		p.API.LogError("failed to save schedule", "schedule_id", schedule.ID, "err", appErr.Error())
+		return
+	}
+	if !started {
+		return
+	}
+
+	when := fmt.Sprintf("%d:%02d", start.Hour(), start.Minute())
+	if start.Day() != now.Day() {
+		when = "tomorrow " + when
+	}
+
+	response, appErr := p.executeCommand(&model.CommandArgs{
+		UserId:    schedule.UserID,
+		ChannelId: schedule.ChannelID,
+		TeamId:    schedule.TeamID,
+		Command:   fmt.Sprintf("/%s start %s", p.getConfiguration().Trigger, when),
+	})
+	if appErr != nil {
+		p.API.LogError("failed to start scheduled game", "schedule_id", schedule.ID, "err", appErr.Error())
+	} else if response.Text != "" {
+		p.API.LogError("scheduled game was not started", "schedule_id", schedule.ID, "reason", response.Text)
+	}
+}
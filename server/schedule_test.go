@@ -0,0 +1,230 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+func TestParseWeekdays(t *testing.T) {
+	tables := []struct {
+		Expression string
+		Weekdays   []time.Weekday
+	}{
+		{"mon-fri", []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}},
+		{"Mo-Fr", []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}},
+		{"mon,wed,friday", []time.Weekday{time.Monday, time.Wednesday, time.Friday}},
+		{"fri-mon", []time.Weekday{time.Monday, time.Friday, time.Saturday, time.Sunday}},
+		{"sun,weekdays", []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Sunday}},
+		{"daily", []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday}},
+	}
+
+	for _, table := range tables {
+		weekdays, ok := parseWeekdays(table.Expression)
+		if !ok || len(weekdays) != len(table.Weekdays) {
+			t.Errorf("Weekdays of %s were incorrect, got: %v, want: %v", table.Expression, weekdays, table.Weekdays)
+			continue
+		}
+		for i := range weekdays {
+			if weekdays[i] != table.Weekdays[i] {
+				t.Errorf("Weekdays of %s were incorrect, got: %v, want: %v", table.Expression, weekdays, table.Weekdays)
+				break
+			}
+		}
+	}
+
+	for _, expression := range []string{"", "12:30", "mon-", "mon-tue-wed", "mon;fri"} {
+		if weekdays, ok := parseWeekdays(expression); ok {
+			t.Errorf("Invalid weekdays %q were accepted: %v", expression, weekdays)
+		}
+	}
+}
+
+func TestFormatWeekdays(t *testing.T) {
+	tr := loadTestTranslations(t).translator("en")
+
+	tables := []struct {
+		Expression string
+		Result     string
+	}{
+		{"mon-fri", "Mon–Fri"},
+		{"mon,tue,thu", "Mon, Tue, Thu"},
+		{"fri-mon", "Mon, Fri–Sun"},
+	}
+
+	for _, table := range tables {
+		weekdays, _ := parseWeekdays(table.Expression)
+		if result := formatWeekdays(weekdays, tr); result != table.Result {
+			t.Errorf("Weekdays %s were formatted incorrectly, got: %s, want: %s", table.Expression, result, table.Result)
+		}
+	}
+}
+
+func TestDueStart(t *testing.T) {
+	loc, _ := time.LoadLocation("Europe/Berlin")
+	weekdays, _ := parseWeekdays("mon-fri")
+	schedule := &Schedule{Weekdays: weekdays, Hour: 12, Minute: 30}
+	noHolidays := func(time.Time) bool { return false }
+
+	// Thursday
+	now := time.Date(2019, time.June, 6, 10, 29, 0, 0, loc)
+	if start, due := schedule.dueStart(now, 2*time.Hour, noHolidays); due {
+		t.Errorf("Poll should not be due before the lead time, got: %s", start)
+	}
+
+	now = now.Add(time.Minute)
+	start, due := schedule.dueStart(now, 2*time.Hour, noHolidays)
+	if !due || !start.Equal(time.Date(2019, time.June, 6, 12, 30, 0, 0, loc)) {
+		t.Errorf("Poll should be due for today, got: %s, %t", start, due)
+	}
+
+	schedule.LastStart = start
+	if start, due = schedule.dueStart(now, 2*time.Hour, noHolidays); due {
+		t.Errorf("Poll should not be started twice, got: %s", start)
+	}
+
+	holiday := func(day time.Time) bool { return day.Day() == 7 }
+	schedule.LastStart = time.Time{}
+	now = time.Date(2019, time.June, 7, 11, 0, 0, 0, loc)
+	if start, due = schedule.dueStart(now, 2*time.Hour, holiday); due {
+		t.Errorf("Poll should not be started on a holiday, got: %s", start)
+	}
+
+	// Sunday evening, with a lead time reaching into Monday
+	now = time.Date(2019, time.June, 9, 23, 0, 0, 0, loc)
+	start, due = schedule.dueStart(now, 14*time.Hour, noHolidays)
+	if !due || !start.Equal(time.Date(2019, time.June, 10, 12, 30, 0, 0, loc)) {
+		t.Errorf("Poll should be due for tomorrow, got: %s, %t", start, due)
+	}
+	if start, due = schedule.dueStart(now.Add(-24*time.Hour), 14*time.Hour, noHolidays); due {
+		t.Errorf("Poll should not be due for a Sunday, got: %s", start)
+	}
+}
+
+func TestIsHoliday(t *testing.T) {
+	c := defaultConfiguration()
+	c.Holidays = "2019-06-10, 12-24,"
+	if err := c.process(); err != nil {
+		t.Fatalf("Valid holidays were rejected: %s", err)
+	}
+
+	for _, day := range []time.Time{
+		time.Date(2019, time.June, 10, 12, 0, 0, 0, time.UTC),
+		time.Date(2019, time.December, 24, 12, 0, 0, 0, time.UTC),
+		time.Date(2020, time.December, 24, 12, 0, 0, 0, time.UTC),
+	} {
+		if !c.isHoliday(day) {
+			t.Errorf("%s should be a holiday", day)
+		}
+	}
+	if c.isHoliday(time.Date(2020, time.June, 10, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Holiday without recurrence should only apply once")
+	}
+
+	c.Holidays = "24.12."
+	if err := c.process(); err == nil {
+		t.Errorf("Invalid holiday was accepted")
+	}
+}
+
+func TestScheduleCommand(t *testing.T) {
+	api := newFakeAPI()
+	p := newTestPlugin(api, loadTestTranslations(t))
+	args := &model.CommandArgs{UserId: "1", ChannelId: "channel", TeamId: "team"}
+
+	execute := func(userID, command string) string {
+		args.UserId = userID
+		response, appErr := p.executeScheduleCommand(args, strings.Fields(command))
+		if appErr != nil {
+			t.Fatalf("Command %q failed: %s", command, appErr.Error())
+		}
+		return response.Text
+	}
+
+	if text := execute("1", "add mon-fri 12:30"); !strings.Contains(text, "Mon–Fri at 12:30 PM in ~channel-channel") {
+		t.Errorf("Schedule was not added, got: %s", text)
+	}
+	if text := execute("1", "add mon,fri 1:15 pm ~channel-other"); !strings.Contains(text, "Mon, Fri at 1:15 PM in ~channel-other") {
+		t.Errorf("Schedule in other channel was not added, got: %s", text)
+	}
+	if text := execute("1", "add mon-fri 12:30 ~unknown"); !strings.Contains(text, "does not exist") {
+		t.Errorf("Schedule in unknown channel should be rejected, got: %s", text)
+	}
+	if text := execute("1", "add noon 12:30"); !strings.Contains(text, "weekdays") {
+		t.Errorf("Invalid weekdays should be rejected, got: %s", text)
+	}
+
+	text := execute("2", "list")
+	if !strings.Contains(text, "1. Mon–Fri at 12:30 PM in ~channel-channel (user1)") || !strings.Contains(text, "2. Mon, Fri at 1:15 PM in ~channel-other (user1)") {
+		t.Errorf("Schedules were not listed, got: %s", text)
+	}
+
+	if text = execute("2", "remove 1"); !strings.Contains(text, "Only") {
+		t.Errorf("Other user should not remove the schedule, got: %s", text)
+	}
+	if text = execute("1", "remove 3"); !strings.Contains(text, "no recurring game") {
+		t.Errorf("Unknown number should be rejected, got: %s", text)
+	}
+	if text = execute("1", "remove 1"); !strings.Contains(text, "Removed: Mon–Fri") {
+		t.Errorf("Schedule was not removed, got: %s", text)
+	}
+
+	if text = execute("1", "list"); strings.Contains(text, "Mon–Fri") || !strings.Contains(text, "1. Mon, Fri") {
+		t.Errorf("Removed schedule is still listed, got: %s", text)
+	}
+}
+
+func TestStartScheduledGames(t *testing.T) {
+	api := newFakeAPI()
+	p := newTestPlugin(api, loadTestTranslations(t))
+	configuration := p.getConfiguration()
+
+	start := time.Now().In(configuration.location).Add(time.Hour)
+	weekdays, _ := parseWeekdays("daily")
+	schedule := &Schedule{
+		ID:        model.NewId(),
+		UserID:    "1",
+		TeamID:    "team",
+		ChannelID: "channel",
+		Weekdays:  weekdays,
+		Hour:      start.Hour(),
+		Minute:    start.Minute(),
+	}
+	if appErr := p.addSchedule(schedule); appErr != nil {
+		t.Fatalf("Failed to save schedule: %s", appErr.Error())
+	}
+
+	p.startScheduledGames(time.Now())
+	game := p.getGame("channel")
+	if game == nil {
+		t.Fatalf("Scheduled game was not started")
+	}
+	defer func() {
+		game.lock.Lock()
+		game.end()
+		game.lock.Unlock()
+	}()
+
+	if expected := start.Truncate(time.Minute); !game.endTime.Equal(expected) || game.userID != "1" {
+		t.Errorf("Scheduled game was started incorrectly, got: %s by %s, want: %s", game.endTime, game.userID, expected)
+	}
+
+	p.startScheduledGames(time.Now())
+	if posts := api.createdPosts(); len(posts) != 1 {
+		t.Errorf("Scheduled game should be started once, got posts: %v", posts)
+	}
+
+	// a schedule removed while its poll is started must not be stored again
+	if appErr := p.removeSchedule(schedule.ID); appErr != nil {
+		t.Fatalf("Failed to remove schedule: %s", appErr.Error())
+	}
+	p.startScheduledGame(schedule, start.Add(24*time.Hour), time.Now())
+	if _, ok := api.kv[scheduleKey(schedule.ID)]; ok {
+		t.Errorf("Removed schedule should not be stored again")
+	}
+	if schedules, _ := p.getSchedules(); len(schedules) != 0 {
+		t.Errorf("Removed schedule should not be listed, got: %v", schedules)
+	}
+}
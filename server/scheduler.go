@@ -0,0 +1,44 @@
+package main
+
+import (
+	"time"
+)
+
+// schedulerInterval is the interval, in which the scheduler checks for due jobs
+const schedulerInterval = time.Minute
+
+// startScheduler starts a goroutine, which runs the scheduled jobs every schedulerInterval.
+// Jobs keep their state in the KV store, so that they continue after a restart.
+func (p *KickerPlugin) startScheduler() {
+	p.schedulerStop = make(chan struct{})
+	stop := p.schedulerStop
+
+	go func() {
+		ticker := time.NewTicker(schedulerInterval)
+		defer ticker.Stop()
+
+		p.runScheduledJobs(time.Now())
+		for {
+			select {
+			case now := <-ticker.C:
+				p.runScheduledJobs(now)
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// stopScheduler stops the goroutine started by startScheduler
+func (p *KickerPlugin) stopScheduler() {
+	if p.schedulerStop != nil {
+		close(p.schedulerStop)
+		p.schedulerStop = nil
+	}
+}
+
+// runScheduledJobs runs all jobs, which are due at the given time
+func (p *KickerPlugin) runScheduledJobs(now time.Time) {
+	p.postWeeklyLeaderboard(now)
+	p.startScheduledGames(now)
+}
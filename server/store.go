@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const (
+	// gameKeyPrefix prefixes the KV store keys of running games, followed by the channel-ID
+	gameKeyPrefix = "game_"
+	// kvListPageSize is the number of keys fetched at once when listing the KV store
+	kvListPageSize = 100
+)
+
+// storedPlayer is the serialized form of a Player
+type storedPlayer struct {
+	UserID    string    `json:"user_id"`
+	WantLevel WantLevel `json:"want_level"`
+	Automatic bool      `json:"automatic,omitempty"`
+}
+
+// storedGame is the serialized form of a Game, as saved in the KV store
+type storedGame struct {
+	ID           string         `json:"id"`
+	PollPostID   string         `json:"poll_post_id"`
+	CancelPostID string         `json:"cancel_post_id"`
+	EndTime      time.Time      `json:"end_time"`
+	UserID       string         `json:"user_id"`
+	ChannelID    string         `json:"channel_id"`
+	RootID       string         `json:"root_id"`
+	Locale       string         `json:"locale"`
+	TeamSize     int            `json:"team_size"`
+	Warned       bool           `json:"warned,omitempty"`
+	Participants []storedPlayer `json:"participants"`
+}
+
+func gameKey(channelID string) string {
+	return gameKeyPrefix + channelID
+}
+
+// marshalGame serializes the state of the given game. The game must be locked.
+func marshalGame(game *Game) ([]byte, error) {
+	stored := storedGame{
+		ID:           game.id,
+		EndTime:      game.endTime,
+		UserID:       game.userID,
+		ChannelID:    game.channelID,
+		RootID:       game.rootID,
+		Locale:       game.locale,
+		TeamSize:     game.teamSize,
+		Warned:       game.warned,
+		Participants: []storedPlayer{},
+	}
+	if game.pollPost != nil {
+		stored.PollPostID = game.pollPost.Id
+	}
+	if game.cancelPost != nil {
+		stored.CancelPostID = game.cancelPost.Id
+	}
+	for _, player := range game.participants {
+		stored.Participants = append(stored.Participants, storedPlayer{
+			UserID:    player.user.Id,
+			WantLevel: player.wantLevel,
+			Automatic: player.automatic,
+		})
+	}
+
+	return json.Marshal(stored)
+}
+
+// saveGame writes the state of the given game to the KV store. The game must be locked.
+func (p *KickerPlugin) saveGame(game *Game) {
+	data, err := marshalGame(game)
+	if err != nil {
+		p.API.LogError("failed to serialize game", "channel_id", game.channelID, "err", err.Error())
+		return
+	}
+
+	if appErr := p.API.KVSet(gameKey(game.channelID), data); appErr != nil {
+		p.API.LogError("failed to save game", "channel_id", game.channelID, "err", appErr.Error())
+	}
+}
+
+// deleteStoredGame removes the given game from the KV store
+func (p *KickerPlugin) deleteStoredGame(game *Game) {
+	if appErr := p.API.KVDelete(gameKey(game.channelID)); appErr != nil {
+		p.API.LogError("failed to delete game", "channel_id", game.channelID, "err", appErr.Error())
+	}
+}
+
+// loadGame reads the game stored with the given key, fetching its posts and users from the Mattermost API
+func (p *KickerPlugin) loadGame(key string) (*Game, *model.AppError) {
+	data, appErr := p.API.KVGet(key)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if data == nil {
+		return nil, appError("game not found", nil)
+	}
+
+	var stored storedGame
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, appError("failed to parse stored game", err)
+	}
+
+	game := NewGame(stored.UserID, stored.ChannelID, stored.RootID)
+	if stored.ID != "" {
+		game.id = stored.ID
+	}
+	game.endTime = stored.EndTime
+	game.locale = stored.Locale
+	game.teamSize = stored.TeamSize
+	game.warned = stored.Warned
+	if game.teamSize == 0 {
+		// stored before games had a format
+		game.teamSize = p.getConfiguration().defaultTeamSize
+	}
+	// ephemeral posts can not be fetched, but deleted by their ID
+	game.cancelPost = &model.Post{Id: stored.CancelPostID}
+
+	game.pollPost, appErr = p.API.GetPost(stored.PollPostID)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	for _, player := range stored.Participants {
+		user, userErr := p.API.GetUser(player.UserID)
+		if userErr != nil {
+			p.API.LogError("failed to get user of stored game", "user_id", player.UserID, "err", userErr.Error())
+			continue
+		}
+		if player.Automatic {
+			game.setAutomaticPlayer(user, player.WantLevel)
+		} else {
+			game.setPlayer(user, player.WantLevel)
+		}
+	}
+
+	return game, nil
+}
+
+// listKeys returns all keys of the KV store with the given prefix
+func (p *KickerPlugin) listKeys(prefix string) ([]string, *model.AppError) {
+	var keys []string
+	for page := 0; ; page++ {
+		pageKeys, appErr := p.API.KVList(page, kvListPageSize)
+		if appErr != nil {
+			return nil, appErr
+		}
+		for _, key := range pageKeys {
+			if strings.HasPrefix(key, prefix) {
+				keys = append(keys, key)
+			}
+		}
+		if len(pageKeys) < kvListPageSize {
+			return keys, nil
+		}
+	}
+}
+
+// restoreGames rehydrates the games stored in the KV store, e.g. after a restart.
+// Timers are re-armed, games that should have ended in the meantime are resolved immediately.
+func (p *KickerPlugin) restoreGames() error {
+	keys, appErr := p.listKeys(gameKeyPrefix)
+	if appErr != nil {
+		return appError("failed to list stored games", appErr)
+	}
+
+	for _, key := range keys {
+		game, loadErr := p.loadGame(key)
+		if loadErr != nil {
+			p.API.LogError("failed to restore game, dropping it", "key", key, "err", loadErr.Error())
+			p.API.KVDelete(key)
+			continue
+		}
+
+		if !p.addGame(game) {
+			continue
+		}
+
+		if !game.endTime.After(time.Now()) {
+			p.CreateEndPollPost(game)
+			continue
+		}
+
+		game.lock.Lock()
+		p.startTimers(game)
+		// games stored by earlier versions get a new ID, which their buttons need to know
+		p.updatePollPost(game)
+		game.lock.Unlock()
+	}
+
+	return nil
+}
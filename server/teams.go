@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+)
+
+// maxBalancedPlayers limits the number of players, for which all team splits are compared
+const maxBalancedPlayers = 16
+
+// positionNames are the message IDs of the positions of a team of two, in the order of the players in a team
+var positionNames = []string{"position.defense", "position.offense"}
+
+// hasRatingData checks if at least one of the given players has a rating from a reported match
+func hasRatingData(players []Player, ratings map[string]*Rating) bool {
+	for _, player := range players {
+		if rating, ok := ratings[player.user.Id]; ok && rating.Games > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// playerRating returns the rating value of the given player, or the initial rating if unknown
+func playerRating(player Player, ratings map[string]*Rating) float64 {
+	if rating, ok := ratings[player.user.Id]; ok {
+		return rating.Value
+	}
+	return initialRating
+}
+
+// averageRating returns the average rating of the given players
+func averageRating(players []Player, ratings map[string]*Rating) float64 {
+	if len(players) == 0 {
+		return initialRating
+	}
+	sum := 0.0
+	for _, player := range players {
+		sum += playerRating(player, ratings)
+	}
+	return sum / float64(len(players))
+}
+
+// shufflePlayers returns the given players in random order
+func shufflePlayers(players []Player) []Player {
+	shuffled := make([]Player, len(players))
+	for i, j := range rand.Perm(len(players)) {
+		shuffled[i] = players[j]
+	}
+	return shuffled
+}
+
+// BalanceTeams divides the players into two teams of equal size. If ratings are available, the split
+// with the smallest difference of the average team ratings is chosen, otherwise the split is random.
+// The players of a team are ordered by position (see positionNames).
+func BalanceTeams(players []Player, ratings map[string]*Rating) [2][]Player {
+	// shuffling first randomizes teams without data, as well as ties and positions
+	shuffled := shufflePlayers(players)
+	if !hasRatingData(players, ratings) || len(players) > maxBalancedPlayers {
+		return splitTeams(shuffled)
+	}
+
+	best := splitTeams(shuffled)
+	bestDiff := math.Inf(1)
+
+	// the first player is always in team A, to skip mirrored splits
+	half := len(shuffled) / 2
+	var choose func(start int, teamA []int)
+	choose = func(start int, teamA []int) {
+		if len(teamA) == half {
+			teams := teamsFromIndexes(shuffled, teamA)
+			diff := math.Abs(averageRating(teams[0], ratings) - averageRating(teams[1], ratings))
+			if diff < bestDiff {
+				best, bestDiff = teams, diff
+			}
+			return
+		}
+		for i := start; i < len(shuffled); i++ {
+			choose(i+1, append(teamA, i))
+		}
+	}
+	choose(1, []int{0})
+
+	return best
+}
+
+// splitMatches divides the players randomly into groups of playerCount players, one per match
+func splitMatches(players []Player, playerCount int) [][]Player {
+	shuffled := shufflePlayers(players)
+	matches := [][]Player{}
+	for len(shuffled) >= playerCount {
+		matches = append(matches, shuffled[:playerCount])
+		shuffled = shuffled[playerCount:]
+	}
+	return matches
+}
+
+// teamsFromIndexes splits the players into the players with the given indexes and the rest
+func teamsFromIndexes(players []Player, teamA []int) [2][]Player {
+	inTeamA := map[int]bool{}
+	for _, i := range teamA {
+		inTeamA[i] = true
+	}
+
+	teams := [2][]Player{{}, {}}
+	for i, player := range players {
+		if inTeamA[i] {
+			teams[0] = append(teams[0], player)
+		} else {
+			teams[1] = append(teams[1], player)
+		}
+	}
+	return teams
+}
+
+// formatTeam returns the players of a team with their ratings, and their positions if the team has two players
+func formatTeam(team []Player, ratings map[string]*Rating, tr translateFunc) string {
+	if len(team) != len(positionNames) {
+		return JoinPlayerNamesWithRatings(team, ratings)
+	}
+
+	names := []string{}
+	for i, player := range team {
+		names = append(names, fmt.Sprintf("%s: %s", tr(positionNames[i]), JoinPlayerNamesWithRatings([]Player{player}, ratings)))
+	}
+	return strings.Join(names, ", ")
+}
+
+// FormatPairing returns the pairing of the teams as "Team A vs Team B"
+func FormatPairing(teams [2][]Player, ratings map[string]*Rating, tr translateFunc) string {
+	return tr("game.pairing", formatTeam(teams[0], ratings, tr), formatTeam(teams[1], ratings, tr))
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestBalanceTeams(t *testing.T) {
+	players := []Player{*horst, *baerbel, *etienne, *ingebork}
+	ratings := map[string]*Rating{
+		"1": {UserID: "1", Value: 1200, Games: 10},
+		"2": {UserID: "2", Value: 1100, Games: 10},
+		"3": {UserID: "3", Value: 1000, Games: 10},
+		"4": {UserID: "4", Value: 900, Games: 10},
+	}
+
+	// the only balanced split is 1200+900 vs 1100+1000
+	for i := 0; i < 20; i++ {
+		teams := BalanceTeams(players, ratings)
+		if len(teams[0]) != 2 || len(teams[1]) != 2 {
+			t.Fatalf("Teams have unexpected sizes: %d, %d", len(teams[0]), len(teams[1]))
+		}
+
+		balanced := []Player{*horst, *ingebork}
+		if !playerEqual(teams[0], balanced) && !playerEqual(teams[1], balanced) {
+			t.Errorf("BalanceTeams returns unbalanced teams: %s vs %s", JoinPlayerNames(teams[0]), JoinPlayerNames(teams[1]))
+		}
+	}
+}
+
+func TestBalanceTeamsWithoutData(t *testing.T) {
+	players := []Player{*horst, *baerbel, *etienne, *ingebork, *kay, *oke}
+
+	teams := BalanceTeams(players, map[string]*Rating{})
+	if len(teams[0]) != 3 || len(teams[1]) != 3 {
+		t.Fatalf("Teams have unexpected sizes: %d, %d", len(teams[0]), len(teams[1]))
+	}
+
+	if !playerEqual(append(append([]Player{}, teams[0]...), teams[1]...), players) {
+		t.Errorf("BalanceTeams lost players: %s vs %s", JoinPlayerNames(teams[0]), JoinPlayerNames(teams[1]))
+	}
+}
+
+func TestFormatPairing(t *testing.T) {
+	teams := [2][]Player{{*horst, *baerbel}, {*etienne, *ingebork}}
+	ratings := map[string]*Rating{"1": {UserID: "1", Value: 1010}}
+
+	de := loadTestTranslations(t).translator("de")
+
+	result := FormatPairing(teams, ratings, de)
+	expected := "**Team A** (Abwehr: horst (1010), Sturm: bärbel) vs **Team B** (Abwehr: etienne, Sturm: ingebork)"
+	if result != expected {
+		t.Errorf("Pairing was incorrect, got: %s, want: %s", result, expected)
+	}
+
+	result = FormatPairing([2][]Player{{*horst}, {*etienne}}, nil, de)
+	if result != "**Team A** (horst) vs **Team B** (etienne)" {
+		t.Errorf("Pairing without positions was incorrect, got: %s", result)
+	}
+}
+
+func TestSplitMatches(t *testing.T) {
+	players := []Player{*horst, *baerbel, *etienne, *ingebork, *kay, *oke, *mable, *uwe}
+
+	matches := splitMatches(players, 4)
+	if len(matches) != 2 || len(matches[0]) != 4 || len(matches[1]) != 4 {
+		t.Fatalf("Players should be split into two matches, got: %v", matches)
+	}
+	if !playerEqual(append(append([]Player{}, matches[0]...), matches[1]...), players) {
+		t.Errorf("Every player should play in one match, got: %v", matches)
+	}
+
+	if matches = splitMatches(players[:3], 2); len(matches) != 1 {
+		t.Errorf("Only full matches should be formed, got: %v", matches)
+	}
+}
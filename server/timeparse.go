@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nowPollDuration is the time players have to answer a poll, which was started with "now"
+const nowPollDuration = 5 * time.Minute
+
+var (
+	// clockPattern matches clock times like "12", "12 30", "12:30", "12.30", "1pm", "1:30 pm" or "12 Uhr"
+	clockPattern = regexp.MustCompile(`^(\d{1,2})(?:(?::|\.|\s+)(\d{1,2}))?\s*(am|pm|uhr)?$`)
+	// durationPattern matches durations like "20m", "1h", "1h30m", "90min" or "1hour30minutes"
+	durationPattern = regexp.MustCompile(`^(?:(\d+)(?:h|hr|hrs|hour|hours|std|stunde|stunden))?(?:(\d+)(?:m|min|mins|minute|minutes|minuten))?$`)
+)
+
+// timeError is an error of ParseTime, with a message meant for the user
+type timeError struct {
+	id   string
+	args []interface{}
+}
+
+func (e *timeError) Error() string {
+	return fmt.Sprintf("%s %v", e.id, e.args)
+}
+
+// translate returns the message of the error for the user
+func (e *timeError) translate(tr translateFunc) string {
+	return tr(e.id, e.args...)
+}
+
+func newTimeError(id string, args ...interface{}) *timeError {
+	return &timeError{id: id, args: args}
+}
+
+/*
+ParseTime parses the time expression entered by the user, i.e. the arguments following the command.
+
+The following expressions are understood, each relative to now and in its location:
+- nothing, using the default hour today
+- clock times like "12", "12 30", "12:30", "1pm" or "1:30 pm"
+- relative times like "in 20m", "in 1h30m" or "in 90 minutes"
+- "now", giving the players nowPollDuration to answer
+- "tomorrow" (or "today"), optionally followed by a clock time
+
+Returns the start time, which is always after now, or an error with a message for the user.
+*/
+func ParseTime(args []string, now time.Time, defaultHour int) (time.Time, *timeError) {
+	words := []string{}
+	for _, arg := range args {
+		words = append(words, strings.ToLower(arg))
+	}
+
+	day := now
+	if len(words) > 0 {
+		switch words[0] {
+		case "now", "jetzt":
+			if len(words) > 1 {
+				return time.Time{}, newTimeError("time.error.after_now")
+			}
+			return now.Add(nowPollDuration), nil
+		case "in":
+			return parseRelativeTime(words[1:], now)
+		case "tomorrow", "morgen":
+			day = now.AddDate(0, 0, 1)
+			words = words[1:]
+		case "today", "heute":
+			words = words[1:]
+		}
+	}
+
+	hour, minute := defaultHour, 0
+	if len(words) > 0 {
+		var err *timeError
+		if hour, minute, err = parseClock(strings.Join(words, " ")); err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	result := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, now.Location())
+	if !result.After(now) {
+		return time.Time{}, newTimeError("time.error.past", hour, minute, hour, minute)
+	}
+	return result, nil
+}
+
+// parseClock parses a clock time, see clockPattern, and returns hour and minute
+func parseClock(expression string) (int, int, *timeError) {
+	matches := clockPattern.FindStringSubmatch(expression)
+	if matches == nil {
+		return 0, 0, newTimeError("time.error.unknown", expression)
+	}
+
+	hour, _ := strconv.Atoi(matches[1])
+	minute := 0
+	if matches[2] != "" {
+		minute, _ = strconv.Atoi(matches[2])
+	}
+
+	switch matches[3] {
+	case "am", "pm":
+		if hour < 1 || hour > 12 {
+			return 0, 0, newTimeError("time.error.am_pm", hour)
+		}
+		hour %= 12
+		if matches[3] == "pm" {
+			hour += 12
+		}
+	default:
+		if hour >= paramMaxHour {
+			return 0, 0, newTimeError("time.error.hour", hour, paramMaxHour-1)
+		}
+	}
+
+	if minute >= paramMaxMinute {
+		return 0, 0, newTimeError("time.error.minute", minute, paramMaxMinute-1)
+	}
+
+	return hour, minute, nil
+}
+
+// parseRelativeTime parses a duration, see durationPattern, and returns now plus the duration
+func parseRelativeTime(words []string, now time.Time) (time.Time, *timeError) {
+	expression := strings.Join(words, "")
+	matches := durationPattern.FindStringSubmatch(expression)
+	if expression == "" || matches == nil {
+		return time.Time{}, newTimeError("time.error.duration", strings.Join(words, " "))
+	}
+
+	hours, _ := strconv.Atoi(matches[1])
+	minutes, _ := strconv.Atoi(matches[2])
+	duration := time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute
+	if duration <= 0 {
+		return time.Time{}, newTimeError("time.error.duration_zero")
+	}
+
+	return now.Add(duration), nil
+}
@@ -1,75 +1,47 @@
 package main
 
 import (
+	"fmt"
 	"net/http"
-	"strconv"
 	"strings"
 	"time"
 
-	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/v5/model"
 )
 
-/*
-ParseArgs parses the arguments entered by the user.
-
-Takes the first 3 arguments:
-- the command "kicker" itself
-- a given hour
-- a given minute
-
-Returns the parsed hour and minute as integers on success.
-*/
-func ParseArgs(args string) ([]int, *model.AppError) {
-	emptyParams := []int{}
-	defaultParams := []int{12}
-	str := strings.SplitN(strings.TrimSpace(args), " ", 3)
-
-	if len(str) == 3 {
-		i1, err1 := strconv.Atoi(str[1])
-		i2, err2 := strconv.Atoi(str[2])
-		if err1 != nil || err2 != nil {
-			return emptyParams, appError("Parsing failed", nil)
-		}
-		// Check for Limits
-		if i1 >= paramMaxHour || i1 < 0 {
-			return emptyParams, appError("Parsing failed", nil)
-		}
-		if i2 >= paramMaxMinute || i2 < 0 {
-			return emptyParams, appError("Parsing failed", nil)
+// JoinPlayerNames concatenates the usernames of the players
+func JoinPlayerNames(players []Player) string {
+	result := ""
+	for index, element := range players {
+		result += element.user.Username
+		if index+1 < len(players) {
+			result += ", "
 		}
-		return []int{
-			i1,
-			i2,
-		}, nil
 	}
+	return result
+}
 
-	if len(str) == 2 {
-		i1, err1 := strconv.Atoi(str[1])
-		if err1 != nil {
-			return emptyParams, appError("Parsing failed", nil)
-		}
-		if i1 >= paramMaxHour || i1 < 0 {
-			return emptyParams, appError("Parsing failed", nil)
+// joinAnswerNames concatenates the usernames of the players, marking automatic answers
+func joinAnswerNames(players []Player, tr translateFunc) string {
+	names := []string{}
+	for _, player := range players {
+		name := player.user.Username
+		if player.automatic {
+			name += " " + tr("poll.automatic")
 		}
-		return []int{
-			i1,
-		}, nil
+		names = append(names, name)
 	}
-
-	if len(str) == 1 && str[0] != "" {
-		// only the trigger was passed, no hour or minute; this is a valid command,
-		// the default hour and minute will be used
-		return defaultParams, nil
-	}
-
-	return emptyParams, appError("Parsing failed", nil)
+	return strings.Join(names, ", ")
 }
 
-// JoinPlayerNames concatenates the usernames of the players
-func JoinPlayerNames(players []Player) string {
+// JoinPlayerNamesWithRatings concatenates the usernames of the players, each followed by the rating
+func JoinPlayerNamesWithRatings(players []Player, ratings map[string]*Rating) string {
 	result := ""
 	for index, element := range players {
 		result += element.user.Username
+		if rating, ok := ratings[element.user.Id]; ok {
+			result += fmt.Sprintf(" (%.0f)", rating.Value)
+		}
 		if index+1 < len(players) {
 			result += ", "
 		}
@@ -77,6 +49,78 @@ func JoinPlayerNames(players []Player) string {
 	return result
 }
 
+// ephemeralResponse returns a command response, which is only visible to the invoking user
+func ephemeralResponse(text string) *model.CommandResponse {
+	return &model.CommandResponse{
+		ResponseType: model.COMMAND_RESPONSE_TYPE_EPHEMERAL,
+		Text:         text,
+	}
+}
+
+// userLocation returns the location of the timezone the given user set in Mattermost,
+// or the fallback if the user has no (valid) timezone
+func userLocation(user *model.User, fallback *time.Location) *time.Location {
+	name := user.Timezone["manualTimezone"]
+	if user.Timezone["useAutomaticTimezone"] == "true" {
+		name = user.Timezone["automaticTimezone"]
+	}
+	if name == "" {
+		return fallback
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fallback
+	}
+	return loc
+}
+
+// formatRelativeDuration returns the given duration as readable text, e.g. "in 1 hour 25 minutes"
+func formatRelativeDuration(d time.Duration, tr translateFunc) string {
+	minutes := int(d.Round(time.Minute).Minutes())
+	if minutes < 1 {
+		return tr("duration.soon")
+	}
+
+	hours := minutes / 60
+	minutes %= 60
+
+	parts := []string{}
+	if hours == 1 {
+		parts = append(parts, tr("duration.hour"))
+	} else if hours > 1 {
+		parts = append(parts, tr("duration.hours", hours))
+	}
+	if minutes == 1 {
+		parts = append(parts, tr("duration.minute"))
+	} else if minutes > 1 {
+		parts = append(parts, tr("duration.minutes", minutes))
+	}
+	return tr("duration.in", strings.Join(parts, " "))
+}
+
+// formatStartTime returns the start time in the given location, including the timezone and
+// the duration until the start, so that users in other timezones can understand it
+func formatStartTime(start time.Time, loc *time.Location, now time.Time, tr translateFunc) string {
+	return tr("time.start", formatLocalTime(start, loc, now, tr), loc.String(), formatRelativeDuration(start.Sub(now), tr))
+}
+
+// formatStartClock returns the start time in the given location including the timezone, but without
+// the duration until the start, as it would go stale in posts, which are not updated regularly
+func formatStartClock(start time.Time, loc *time.Location, now time.Time, tr translateFunc) string {
+	return tr("time.start_clock", formatLocalTime(start, loc, now, tr), loc.String())
+}
+
+// formatLocalTime returns the clock time in the given location, prefixed by the day if it is not today
+func formatLocalTime(t time.Time, loc *time.Location, now time.Time, tr translateFunc) string {
+	local := t.In(loc)
+	day := ""
+	if y, m, d := now.In(loc).Date(); local.Year() != y || local.Month() != m || local.Day() != d {
+		day = local.Format(tr("time.format.day"))
+	}
+	return day + local.Format(tr("time.format.clock"))
+}
+
 func appError(message string, err error) *model.AppError {
 	errorMessage := ""
 	if err != nil {
@@ -91,20 +135,3 @@ func remove(s []Player, i int) []Player {
 	s[i] = s[len(s)-1]
 	return s[:len(s)-1]
 }
-
-func getEndTime(params ...int) time.Time {
-	// default values
-	hour, minute := 12, 0
-
-	if len(params) == 2 {
-		hour, minute = params[0], params[1]
-	}
-
-	if len(params) == 1 {
-		hour = params[0]
-	}
-	loc, _ := time.LoadLocation("Europe/Berlin")
-
-	n := time.Now()
-	return time.Date(n.Year(), n.Month(), n.Day(), 0, 0, 0, 0, loc).Add(time.Hour * time.Duration(hour)).Add(time.Minute * time.Duration(minute))
-}